@@ -1,4 +1,8 @@
+DROP TABLE ship_photos;
+DROP TABLE request_ship_events;
 DROP TABLE ships_in_request;
 DROP TABLE ships;
 DROP TABLE request_ship;
+DROP TABLE berths;
+DROP TABLE terminals;
 DROP TABLE users
@@ -23,9 +23,38 @@ CREATE TABLE ships (
     draft DECIMAL(10,2),
     cranes INTEGER,
     containers INTEGER,
-    photo_url VARCHAR(500) NULL
+    photo_url VARCHAR(500) NULL,
+    -- полнотекстовый поиск по каталогу (repository/ship_search.go): название важнее описания;
+    -- выражение совпадает с repository.ShipSearchVectorSQL (проверяется тестом)
+    search_vector TSVECTOR GENERATED ALWAYS AS (
+        setweight(to_tsvector('russian', coalesce(name, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(name, '')), 'A') ||
+        setweight(to_tsvector('russian', coalesce(description, '')), 'B') ||
+        setweight(to_tsvector('english', coalesce(description, '')), 'B')
+    ) STORED
 );
 
+CREATE INDEX idx_ships_search_vector ON ships USING GIN (search_vector);
+
+-- 2a. Терминалы и причалы (соответствуют моделям Terminal и Berth); 0 - без ограничения
+CREATE TABLE terminals (
+    terminal_id SERIAL PRIMARY KEY,
+    name VARCHAR(200) NOT NULL,
+    port VARCHAR(200) NOT NULL DEFAULT ''
+);
+
+CREATE TABLE berths (
+    berth_id SERIAL PRIMARY KEY,
+    terminal_id INTEGER NOT NULL REFERENCES terminals(terminal_id),
+    name VARCHAR(200) NOT NULL,
+    max_length DECIMAL(10,2) NOT NULL DEFAULT 0,
+    max_beam DECIMAL(10,2) NOT NULL DEFAULT 0,
+    max_depth DECIMAL(10,2) NOT NULL DEFAULT 0,
+    cranes INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX idx_berths_terminal_id ON berths (terminal_id);
+
 -- 3. Таблица заявок (соответствует модели RequestShip)
 CREATE TABLE request_ship (
     request_ship_id SERIAL PRIMARY KEY,  
@@ -33,13 +62,18 @@ CREATE TABLE request_ship (
     creation_date TIMESTAMP DEFAULT CURRENT_TIMESTAMP, 
     formation_date TIMESTAMP NULL, 
     completion_date TIMESTAMP NULL, 
+    deletion_date TIMESTAMP NULL,
     moderator_id INTEGER NULL REFERENCES users(user_id),
+    rejection_reason TEXT,
     user_id INTEGER NOT NULL REFERENCES users(user_id),
+    berth_id INTEGER NULL REFERENCES berths(berth_id),
     
     containers_20ft_count INTEGER DEFAULT NULL,
     containers_40ft_count INTEGER DEFAULT NULL,
     comment TEXT,
-    loading_time DECIMAL(10,2) DEFAULT NULL
+    loading_time DECIMAL(10,2) DEFAULT NULL,
+    calculation_strategy VARCHAR(30) NULL,
+    calculation_params TEXT NULL
 );
 
 -- 4. М-М таблица (соответствует модели ShipInRequest) 
@@ -50,6 +84,30 @@ CREATE TABLE ships_in_request (
     PRIMARY KEY (request_ship_id, ship_id)
 );
 
+-- 4a. История изменений заявок (соответствует модели RequestShipEvent)
+CREATE TABLE request_ship_events (
+    event_id SERIAL PRIMARY KEY,
+    request_ship_id INTEGER NOT NULL REFERENCES request_ship(request_ship_id),
+    actor_id INTEGER NOT NULL REFERENCES users(user_id),
+    event_type VARCHAR(30) NOT NULL,
+    ship_id INTEGER NULL REFERENCES ships(ship_id),
+    old_value TEXT,
+    new_value TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_request_ship_events_request_ship_id ON request_ship_events (request_ship_id);
+
+-- 4b. Галерея фотографий кораблей (соответствует модели ShipPhoto), основная фотография - ships.photo_url
+CREATE TABLE ship_photos (
+    photo_id SERIAL PRIMARY KEY,
+    ship_id INTEGER NOT NULL REFERENCES ships(ship_id) ON DELETE CASCADE,
+    file_name VARCHAR(500) NOT NULL,
+    caption TEXT NOT NULL DEFAULT '',
+    position INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_ship_photos_ship_id ON ship_photos (ship_id);
+
 -- 5. Ограничение одной черновой заявки
 CREATE UNIQUE INDEX one_draft_request_per_user 
 ON request_ship (user_id) 
@@ -73,6 +131,20 @@ INSERT INTO ships (name, description, capacity, length, width, draft, cranes, co
 ('HMM Algeciras', 'двигатель MAN B&W 11G95ME-C9.5 мощностью 64 000 кВт, двойные двигатели, система рекуперации энергии, класс DNV GL', 23964, 399.9, 61.0, 16.5, 7, 11982, 'hmm-algeciras.png'),
 ('MSC Gulsun', 'первый в мире контейнеровоз, вмещающий более 23 000 TEU, двигатель MAN B&W 11G95ME-C9.5, класс DNV GL', 23756, 399.9, 61.4, 16.0, 7, 11878, 'msc-gulsun.png');
 
+INSERT INTO ship_photos (ship_id, file_name, position)
+SELECT ship_id, photo_url, 0 FROM ships WHERE photo_url IS NOT NULL AND photo_url <> '';
+
+-- 7a. Терминалы и причалы
+INSERT INTO terminals (name, port) VALUES
+('Первый контейнерный терминал', 'Санкт-Петербург'),
+('Восточная стивидорная компания', 'Находка');
+
+INSERT INTO berths (terminal_id, name, max_length, max_beam, max_depth, cranes) VALUES
+(1, 'Причал 83', 300, 43, 11.0, 2),
+(1, 'Причал 84', 366, 51, 13.0, 4),
+(2, 'Причал 1', 400, 62, 17.5, 6),
+(2, 'Причал 2', 240, 35, 12.0, 2);
+
 -- 8. Демо-заявка
 INSERT INTO request_ship (status, user_id, comment) VALUES 
 ('черновик', 1, 'Демо-заявка для тестирования');
\ No newline at end of file
@@ -0,0 +1,123 @@
+package main
+
+// Сборщик неиспользуемых изображений кораблей.
+// go run ./cmd/loading_time/imagegc              - только отчёт (dry-run)
+// go run ./cmd/loading_time/imagegc -dry-run=false             - удалить
+// go run ./cmd/loading_time/imagegc -dry-run=false -quarantine - перенести в quarantine/
+
+import (
+	"context"
+	"flag"
+	"time"
+
+	"loading_time/internal/app/config"
+	"loading_time/internal/app/ds"
+	"loading_time/internal/app/dsn"
+	"loading_time/internal/app/images"
+	"loading_time/internal/app/storage"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", true, "only report unreferenced objects, do not change the store")
+	quarantine := flag.Bool("quarantine", false, "move unreferenced objects to "+storage.QuarantinePrefix+" instead of deleting them")
+	minAge := flag.Duration("min-age", 24*time.Hour, "ignore objects modified more recently (uploads in progress)")
+	prefix := flag.String("prefix", images.ObjectName(""), "object name prefix to scan")
+	flag.Parse()
+
+	conf, err := config.NewConfig()
+	if err != nil {
+		logrus.Fatalf("error loading config: %v", err)
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn.FromEnv()), &gorm.Config{})
+	if err != nil {
+		logrus.Fatalf("error connecting to database: %v", err)
+	}
+
+	store, err := storage.FromConfig(conf.ImageStore)
+	if err != nil {
+		logrus.Fatalf("error initializing image store: %v", err)
+	}
+
+	ctx := context.Background()
+
+	// Список объектов берём до чтения ссылок из БД: объект, загруженный и сохранённый
+	// между этими шагами, уже будет в referenced или окажется моложе min-age
+	objects, err := store.List(ctx, *prefix)
+	if err != nil {
+		logrus.Fatalf("error listing objects: %v", err)
+	}
+
+	referenced, err := referencedObjects(db)
+	if err != nil {
+		logrus.Fatalf("error loading image references: %v", err)
+	}
+
+	var orphans, skipped, failed int
+	var orphanBytes int64
+	cutoff := time.Now().Add(-*minAge)
+	for _, object := range objects {
+		if referenced[object.Name] {
+			continue
+		}
+		if object.LastModified.After(cutoff) {
+			skipped++
+			logrus.Infof("skip recent %s (modified %s)", object.Name, object.LastModified.Format(time.RFC3339))
+			continue
+		}
+
+		orphans++
+		orphanBytes += object.Size
+		logrus.Infof("orphan %s (%d bytes, modified %s)", object.Name, object.Size, object.LastModified.Format(time.RFC3339))
+		if *dryRun {
+			continue
+		}
+
+		if *quarantine {
+			err = store.Move(ctx, object.Name, storage.QuarantinePrefix+object.Name)
+		} else {
+			err = store.Remove(ctx, object.Name)
+		}
+		if err != nil {
+			failed++
+			logrus.Errorf("failed to collect %s: %v", object.Name, err)
+		}
+	}
+
+	action := "deleted"
+	switch {
+	case *dryRun:
+		action = "would be collected (dry-run)"
+	case *quarantine:
+		action = "moved to " + storage.QuarantinePrefix
+	}
+	logrus.Infof("scanned %d objects under %q: %d referenced, %d recent skipped, %d unreferenced (%d bytes) %s, %d failed",
+		len(objects), *prefix, len(objects)-orphans-skipped, skipped, orphans, orphanBytes, action, failed)
+}
+
+// referencedObjects - имена объектов, на которые ссылаются ships.photo_url и ship_photos,
+// вместе с уменьшенными копиями
+func referencedObjects(db *gorm.DB) (map[string]bool, error) {
+	var fileNames, galleryNames []string
+	if err := db.Model(&ds.Ship{}).Where("photo_url IS NOT NULL AND photo_url <> ''").
+		Pluck("photo_url", &fileNames).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Model(&ds.ShipPhoto{}).Pluck("file_name", &galleryNames).Error; err != nil {
+		return nil, err
+	}
+	fileNames = append(fileNames, galleryNames...)
+
+	referenced := make(map[string]bool, len(fileNames)*(len(images.Renditions())+1))
+	for _, fileName := range fileNames {
+		referenced[images.ObjectName(fileName)] = true
+		for _, rendition := range images.Renditions() {
+			referenced[images.ObjectName(images.RenditionName(fileName, rendition))] = true
+		}
+	}
+	return referenced, nil
+}
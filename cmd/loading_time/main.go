@@ -3,13 +3,15 @@ package main
 // go run cmd/loading_time/main.go
 
 import (
-	"fmt"
+	"loading_time/internal/app/auth"
+	"loading_time/internal/app/calculator"
 	"loading_time/internal/app/config"
 	"loading_time/internal/app/dsn"
 	"loading_time/internal/app/handler"
 	"loading_time/internal/app/pkg"
 	"loading_time/internal/app/repository"
-	"loading_time/internal/app/utils"
+	"loading_time/internal/app/session"
+	"loading_time/internal/app/storage"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -22,24 +24,25 @@ import (
 )
 
 func main() {
-	utils.InitRedis()
 	gin.SetMode(gin.ReleaseMode)
 	router := gin.Default()
 	router.Use(func(c *gin.Context) {
 		logrus.Infof("Incoming request: %s %s", c.Request.Method, c.Request.URL.Path)
 	})
 
-	router.LoadHTMLGlob("templates/*.html")
-
 	conf, err := config.NewConfig()
 	if err != nil {
 		logrus.Fatalf("error loading config: %v", err)
 	}
 
 	postgresString := dsn.FromEnv()
-	fmt.Println(postgresString)
 
-	rep, errRep := repository.New(postgresString, conf.RedisEndpoint, conf.RedisPassword, conf.JwtKey)
+	calc, err := calculator.FromConfig(conf.Calculator)
+	if err != nil {
+		logrus.Fatalf("error configuring loading time calculator: %v", err)
+	}
+
+	rep, errRep := repository.New(postgresString, calc)
 	if errRep != nil {
 		logrus.Fatalf("error initializing repository: %v", errRep)
 	}
@@ -51,7 +54,25 @@ func main() {
 		},
 	}
 
-	hand := handler.NewHandler(rep)
+	imageStore, err := storage.FromConfig(conf.ImageStore)
+	if err != nil {
+		logrus.Fatalf("error initializing image store: %v", err)
+	}
+
+	tokens, err := auth.NewTokenService(conf.JWT)
+	if err != nil {
+		logrus.Fatalf("error configuring jwt: %v", err)
+	}
+
+	sessions, err := session.FromConfig(conf)
+	if err != nil {
+		logrus.Fatalf("error initializing session store: %v", err)
+	}
+
+	hand := handler.NewHandler(rep, imageStore, tokens, sessions, conf)
+
+	router.SetFuncMap(hand.TemplateFuncs())
+	router.LoadHTMLGlob("templates/*.html")
 
 	router.Use(func(c *gin.Context) {
 		if m := c.PostForm("_method"); m != "" {
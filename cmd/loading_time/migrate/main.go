@@ -4,6 +4,7 @@ import (
 	"loading_time/internal/app/config"
 	"loading_time/internal/app/ds"
 	"loading_time/internal/app/dsn"
+	"loading_time/internal/app/repository"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/driver/postgres"
@@ -22,11 +23,15 @@ func main() {
 		logrus.Fatalf("error connecting to database: %v", err)
 	}
 
-	// Порядок миграций: сначала users, потом request_ship, ships, ships_in_request
+	// Порядок миграций: сначала users, terminals и berths, потом request_ship, ships, ships_in_request, request_ship_events, ship_photos
 	err = db.AutoMigrate(&ds.User{})
 	if err != nil {
 		logrus.Fatalf("error migrating users: %v", err)
 	}
+	err = db.AutoMigrate(&ds.Terminal{}, &ds.Berth{})
+	if err != nil {
+		logrus.Fatalf("error migrating terminals and berths: %v", err)
+	}
 	err = db.AutoMigrate(&ds.RequestShip{})
 	if err != nil {
 		logrus.Fatalf("error migrating request_ship: %v", err)
@@ -39,6 +44,35 @@ func main() {
 	if err != nil {
 		logrus.Fatalf("error migrating ships_in_request: %v", err)
 	}
+	err = db.AutoMigrate(&ds.RequestShipEvent{})
+	if err != nil {
+		logrus.Fatalf("error migrating request_ship_events: %v", err)
+	}
+	err = db.AutoMigrate(&ds.ShipPhoto{})
+	if err != nil {
+		logrus.Fatalf("error migrating ship_photos: %v", err)
+	}
+
+	// Фотографии, загруженные до появления галереи, переносим в ship_photos
+	err = db.Exec(`INSERT INTO ship_photos (ship_id, file_name, caption, position, created_at)
+		SELECT s.ship_id, s.photo_url, '', 0, NOW() FROM ships s
+		WHERE s.photo_url <> '' AND NOT EXISTS (
+			SELECT 1 FROM ship_photos p WHERE p.ship_id = s.ship_id AND p.file_name = s.photo_url)`).Error
+	if err != nil {
+		logrus.Fatalf("error filling ship_photos: %v", err)
+	}
+
+	// Полнотекстовый поиск по каталогу: генерируемый столбец не описан в ds.Ship,
+	// чтобы gorm не пытался писать в него при Create/Save
+	err = db.Exec(`ALTER TABLE ships ADD COLUMN IF NOT EXISTS search_vector tsvector
+		GENERATED ALWAYS AS (` + repository.ShipSearchVectorSQL() + `) STORED`).Error
+	if err != nil {
+		logrus.Fatalf("error adding ships.search_vector: %v", err)
+	}
+	err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_ships_search_vector ON ships USING GIN (search_vector)`).Error
+	if err != nil {
+		logrus.Fatalf("error indexing ships.search_vector: %v", err)
+	}
 
 	logrus.Info("Database migration completed")
 }
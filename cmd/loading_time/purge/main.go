@@ -0,0 +1,57 @@
+package main
+
+// Окончательное удаление заявок, удалённых владельцами раньше срока хранения
+// (RequestShip.DeletedRetention в конфигурации).
+// go run ./cmd/loading_time/purge               - только отчёт (dry-run)
+// go run ./cmd/loading_time/purge -dry-run=false - удалить
+
+import (
+	"flag"
+	"time"
+
+	"loading_time/internal/app/config"
+	"loading_time/internal/app/dsn"
+	"loading_time/internal/app/repository"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", true, "only report expired requests, do not delete them")
+	retention := flag.Duration("retention", 0, "override RequestShip.DeletedRetention from config")
+	flag.Parse()
+
+	conf, err := config.NewConfig()
+	if err != nil {
+		logrus.Fatalf("error loading config: %v", err)
+	}
+
+	if *retention <= 0 {
+		*retention = conf.RequestShip.DeletedRetention
+	}
+	if *retention <= 0 {
+		logrus.Fatal("retention period must be configured (RequestShip.DeletedRetention or -retention)")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn.FromEnv()), &gorm.Config{})
+	if err != nil {
+		logrus.Fatalf("error connecting to database: %v", err)
+	}
+
+	deletedBefore := time.Now().Add(-*retention)
+	ids, err := repository.PurgeExpiredRequestShips(db, deletedBefore, *dryRun)
+	for _, id := range ids {
+		logrus.Infof("request_ship_id=%d deleted before %s", id, deletedBefore.Format(time.RFC3339))
+	}
+	if err != nil {
+		logrus.Fatalf("error purging requests (%d purged): %v", len(ids), err)
+	}
+
+	if *dryRun {
+		logrus.Infof("%d expired requests would be purged (dry-run)", len(ids))
+		return
+	}
+	logrus.Infof("%d expired requests purged", len(ids))
+}
@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"loading_time/internal/app/config"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// ErrInvalidToken - токен не прошёл проверку: подпись, kid, срок, издатель или аудитория
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// defaultTTL - срок жизни токена, если JWT.TTL не задан в конфигурации
+const defaultTTL = 24 * time.Hour
+
+// Claims - содержимое токена доступа
+type Claims struct {
+	UserID int    `json:"user_id"`
+	Role   string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+// TokenService - выпуск и проверка токенов доступа (HS256) по config.JWTConfig.
+// Новые токены подписываются ключом SigningKeyID, его kid пишется в заголовок;
+// при проверке принимается любой ключ из Keys, поэтому ключ можно сменить,
+// не отзывая уже выданные токены: новый ключ делают SigningKeyID, старый
+// остаётся в Keys, пока не истечёт TTL последнего подписанного им токена.
+type TokenService struct {
+	issuer     string
+	audience   string
+	ttl        time.Duration
+	signingKID string
+	keys       map[string][]byte
+}
+
+// NewTokenService - сервис токенов из конфигурации; ключ SigningKeyID обязателен
+func NewTokenService(cfg config.JWTConfig) (*TokenService, error) {
+	if cfg.Issuer == "" || cfg.Audience == "" {
+		return nil, errors.New("jwt: issuer and audience must be set")
+	}
+
+	keys := make(map[string][]byte, len(cfg.Keys))
+	for kid, secret := range cfg.Keys {
+		if secret == "" {
+			return nil, fmt.Errorf("jwt: empty secret for key %q", kid)
+		}
+		keys[strings.ToLower(kid)] = []byte(secret)
+	}
+
+	signingKID := strings.ToLower(cfg.SigningKeyID)
+	if _, ok := keys[signingKID]; !ok {
+		return nil, fmt.Errorf("jwt: no secret for signing key %q (set JWT_KEY or JWT.Keys)", cfg.SigningKeyID)
+	}
+
+	ttl := cfg.TTL
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+
+	return &TokenService{
+		issuer:     cfg.Issuer,
+		audience:   cfg.Audience,
+		ttl:        ttl,
+		signingKID: signingKID,
+		keys:       keys,
+	}, nil
+}
+
+// TTL - срок жизни выпускаемых токенов
+func (s *TokenService) TTL() time.Duration {
+	return s.ttl
+}
+
+// Issue - подписанный токен доступа и его claims (ID - уникальный jti токена)
+func (s *TokenService) Issue(userID int, role string) (string, *Claims, error) {
+	now := time.Now()
+	claims := &Claims{
+		UserID: userID,
+		Role:   role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Issuer:    s.issuer,
+			Subject:   strconv.Itoa(userID),
+			Audience:  jwt.ClaimStrings{s.audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.ttl)),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = s.signingKID
+	signed, err := token.SignedString(s.keys[s.signingKID])
+	if err != nil {
+		return "", nil, err
+	}
+	return signed, claims, nil
+}
+
+// Parse - проверить токен и вернуть его claims; любая ошибка проверки - ErrInvalidToken
+func (s *TokenService) Parse(tokenStr string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenStr, claims, s.key,
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(s.issuer),
+		jwt.WithAudience(s.audience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	return claims, nil
+}
+
+// key - секрет по kid из заголовка токена
+func (s *TokenService) key(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	secret, ok := s.keys[strings.ToLower(kid)]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	return secret, nil
+}
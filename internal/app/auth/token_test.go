@@ -0,0 +1,163 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"loading_time/internal/app/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func testConfig() config.JWTConfig {
+	return config.JWTConfig{
+		Issuer:       "loading_time",
+		Audience:     "loading_time_api",
+		TTL:          time.Hour,
+		SigningKeyID: "main",
+		Keys:         map[string]string{"main": "main-secret"},
+	}
+}
+
+func newTestService(t *testing.T, cfg config.JWTConfig) *TokenService {
+	t.Helper()
+	tokens, err := NewTokenService(cfg)
+	if err != nil {
+		t.Fatalf("NewTokenService: %v", err)
+	}
+	return tokens
+}
+
+// signed - токен с произвольными claims, подписанный secret с заголовком kid
+func signed(t *testing.T, kid, secret string, claims *Claims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = kid
+	str, err := token.SignedString([]byte(secret))
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+	return str
+}
+
+func validClaims() *Claims {
+	now := time.Now()
+	return &Claims{
+		UserID: 1,
+		Role:   "creator",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "loading_time",
+			Audience:  jwt.ClaimStrings{"loading_time_api"},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+		},
+	}
+}
+
+func TestIssueParse(t *testing.T) {
+	tokens := newTestService(t, testConfig())
+
+	str, issued, err := tokens.Issue(42, "moderator")
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if issued.ID == "" {
+		t.Error("Issue: empty jti")
+	}
+
+	claims, err := tokens.Parse(str)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if claims.UserID != 42 || claims.Role != "moderator" || claims.ID != issued.ID {
+		t.Errorf("Parse = %+v, want user 42, moderator, jti %s", claims, issued.ID)
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	tokens := newTestService(t, testConfig())
+
+	wrongIssuer := validClaims()
+	wrongIssuer.Issuer = "someone_else"
+
+	wrongAudience := validClaims()
+	wrongAudience.Audience = jwt.ClaimStrings{"other_api"}
+
+	expired := validClaims()
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+
+	noExpiry := validClaims()
+	noExpiry.ExpiresAt = nil
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"wrong issuer", signed(t, "main", "main-secret", wrongIssuer)},
+		{"wrong audience", signed(t, "main", "main-secret", wrongAudience)},
+		{"expired", signed(t, "main", "main-secret", expired)},
+		{"no expiry", signed(t, "main", "main-secret", noExpiry)},
+		{"unknown kid", signed(t, "other", "main-secret", validClaims())},
+		{"wrong secret", signed(t, "main", "not-the-secret", validClaims())},
+		{"garbage", "not.a.token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tokens.Parse(tt.token); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("Parse: err = %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+}
+
+func TestParseAcceptsValidClaims(t *testing.T) {
+	tokens := newTestService(t, testConfig())
+	if _, err := tokens.Parse(signed(t, "main", "main-secret", validClaims())); err != nil {
+		t.Errorf("Parse: %v", err)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	// до ротации токены подписываются ключом "old"
+	before := testConfig()
+	before.SigningKeyID = "old"
+	before.Keys = map[string]string{"old": "old-secret"}
+	oldToken, _, err := newTestService(t, before).Issue(1, "creator")
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	// после ротации подписывает "new", но "old" ещё в Keys
+	during := testConfig()
+	during.SigningKeyID = "new"
+	during.Keys = map[string]string{"new": "new-secret", "old": "old-secret"}
+	rotated := newTestService(t, during)
+
+	if _, err := rotated.Parse(oldToken); err != nil {
+		t.Errorf("Parse old-key token during rotation: %v", err)
+	}
+	newToken, _, err := rotated.Issue(1, "creator")
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if _, err := rotated.Parse(newToken); err != nil {
+		t.Errorf("Parse new-key token: %v", err)
+	}
+
+	// старый ключ убран из Keys - его токены больше не принимаются
+	after := testConfig()
+	after.SigningKeyID = "new"
+	after.Keys = map[string]string{"new": "new-secret"}
+	if _, err := newTestService(t, after).Parse(oldToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Parse old-key token after rotation: err = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestNewTokenServiceRequiresSigningKey(t *testing.T) {
+	cfg := testConfig()
+	cfg.SigningKeyID = "missing"
+	if _, err := NewTokenService(cfg); err == nil {
+		t.Error("NewTokenService without a secret for SigningKeyID: want error")
+	}
+}
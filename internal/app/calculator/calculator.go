@@ -0,0 +1,140 @@
+package calculator
+
+import (
+	"fmt"
+	"math"
+
+	"loading_time/internal/app/config"
+	"loading_time/internal/app/ds"
+)
+
+const (
+	StrategyDefault      = "default"
+	StrategyConfigurable = "configurable"
+)
+
+// LoadingTimeCalculator - стратегия расчёта времени погрузки заявки.
+// Name и Params сохраняются в заявке, чтобы старый результат можно было воспроизвести через New.
+type LoadingTimeCalculator interface {
+	Name() string
+	Params() map[string]float64
+	Calculate(requestShip ds.RequestShip) float64
+}
+
+// New - восстановить стратегию по имени и параметрам (из конфига или из сохранённой заявки)
+func New(name string, params map[string]float64) (LoadingTimeCalculator, error) {
+	switch name {
+	case "", StrategyDefault:
+		return DefaultCalculator{}, nil
+	case StrategyConfigurable:
+		return ConfigurableCalculator{
+			Hours20ft:          params["hours_20ft"],
+			Hours40ft:          params["hours_40ft"],
+			CraneEfficiency:    params["crane_efficiency"],
+			ShiftHours:         params["shift_hours"],
+			ShiftOverheadHours: params["shift_overhead_hours"],
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown loading time strategy %q", name)
+	}
+}
+
+// FromConfig - стратегия, выбранная в config.Config
+func FromConfig(cfg config.CalculatorConfig) (LoadingTimeCalculator, error) {
+	return New(cfg.Strategy, map[string]float64{
+		"hours_20ft":           cfg.Hours20ft,
+		"hours_40ft":           cfg.Hours40ft,
+		"crane_efficiency":     cfg.CraneEfficiency,
+		"shift_hours":          cfg.ShiftHours,
+		"shift_overhead_hours": cfg.ShiftOverheadHours,
+	})
+}
+
+// totalCranes - суммарное число кранов всех кораблей заявки и береговых кранов её причала
+func totalCranes(requestShip ds.RequestShip) int {
+	cranes := berthCranes(requestShip)
+	for _, shipInRequest := range requestShip.Ships {
+		cranes += shipInRequest.Ship.Cranes * shipInRequest.ShipsCount
+	}
+	return cranes
+}
+
+// berthCranes - береговые краны причала заявки (0, если причал не выбран)
+func berthCranes(requestShip ds.RequestShip) int {
+	if requestShip.Berth == nil {
+		return 0
+	}
+	return requestShip.Berth.Cranes
+}
+
+// DefaultCalculator - исходная формула: (20ft * 2 + 40ft * 3) / количество кранов,
+// где 2 и 3 - часы на погрузку одного 20ft и одного 40ft контейнера,
+// а краны - краны кораблей вместе с береговыми кранами причала
+type DefaultCalculator struct{}
+
+func (DefaultCalculator) Name() string {
+	return StrategyDefault
+}
+
+func (DefaultCalculator) Params() map[string]float64 {
+	return map[string]float64{
+		"hours_20ft": 2,
+		"hours_40ft": 3,
+	}
+}
+
+func (DefaultCalculator) Calculate(requestShip ds.RequestShip) float64 {
+	cranes := totalCranes(requestShip)
+	if cranes == 0 {
+		return 0
+	}
+
+	totalContainerTime := float64(requestShip.Containers20ftCount)*2 + float64(requestShip.Containers40ftCount)*3
+	return totalContainerTime / float64(cranes)
+}
+
+// ConfigurableCalculator - та же модель с настраиваемыми параметрами:
+// часы на контейнер каждого типа, КПД крана и потери времени на пересменку
+type ConfigurableCalculator struct {
+	Hours20ft          float64
+	Hours40ft          float64
+	CraneEfficiency    float64 // доля номинальной производительности крана, (0; 1]; 0 считается как 1
+	ShiftHours         float64 // длительность смены; 0 - без учёта пересменок
+	ShiftOverheadHours float64 // потери на каждую пересменку
+}
+
+func (c ConfigurableCalculator) Name() string {
+	return StrategyConfigurable
+}
+
+func (c ConfigurableCalculator) Params() map[string]float64 {
+	return map[string]float64{
+		"hours_20ft":           c.Hours20ft,
+		"hours_40ft":           c.Hours40ft,
+		"crane_efficiency":     c.CraneEfficiency,
+		"shift_hours":          c.ShiftHours,
+		"shift_overhead_hours": c.ShiftOverheadHours,
+	}
+}
+
+func (c ConfigurableCalculator) Calculate(requestShip ds.RequestShip) float64 {
+	cranes := totalCranes(requestShip)
+	if cranes == 0 {
+		return 0
+	}
+
+	efficiency := c.CraneEfficiency
+	if efficiency <= 0 {
+		efficiency = 1
+	}
+
+	totalContainerTime := float64(requestShip.Containers20ftCount)*c.Hours20ft + float64(requestShip.Containers40ftCount)*c.Hours40ft
+	loadingTime := totalContainerTime / (float64(cranes) * efficiency)
+
+	// каждая пересменка между сменами добавляет ShiftOverheadHours
+	if c.ShiftHours > 0 && loadingTime > c.ShiftHours {
+		shiftChanges := math.Ceil(loadingTime/c.ShiftHours) - 1
+		loadingTime += shiftChanges * c.ShiftOverheadHours
+	}
+	return loadingTime
+}
@@ -0,0 +1,145 @@
+package calculator
+
+import (
+	"sort"
+
+	"loading_time/internal/app/ds"
+)
+
+const (
+	ObjectiveFewestShips    = "fewest_ships"
+	ObjectiveMinLoadingTime = "min_loading_time"
+
+	// DefaultMaxShips - сколько кораблей (с повторами) максимум перебирается в одной рекомендации
+	DefaultMaxShips = 6
+	// MaxShipsLimit - верхняя граница MaxShips: перебор растёт с ней комбинаторно
+	MaxShipsLimit = 20
+
+	// recommendSearchBudget - ограничение числа узлов перебора, чтобы большой каталог не подвесил запрос
+	recommendSearchBudget = 200000
+)
+
+// RecommendationRequest - что нужно погрузить и что оптимизировать
+type RecommendationRequest struct {
+	Containers20ft int
+	Containers40ft int
+	DeadlineHours  float64 // 0 - без ограничения
+	MaxShips       int     // 0 - DefaultMaxShips, не больше MaxShipsLimit
+	Objective      string  // ObjectiveFewestShips (по умолчанию) | ObjectiveMinLoadingTime
+}
+
+// Recommendation - подобранный набор кораблей
+type Recommendation struct {
+	Ships       []ds.ShipInRequest
+	ShipsCount  int
+	LoadingTime float64
+	CapacityTEU float64
+	// Truncated - перебор остановлен по лимиту узлов: найденный набор может быть не лучшим,
+	// а "не найдено" не означает, что подходящего набора нет
+	Truncated bool
+}
+
+// Recommend - перебор наборов кораблей каталога (с повторами) с отсечением по вместимости.
+// Набор подходит, если контейнеры помещаются (TEU и места под 40ft) и время погрузки,
+// посчитанное стратегией calc, укладывается в срок. Среди подходящих выбирается набор
+// с наименьшим числом кораблей (при равенстве - с меньшим временем) или наоборот,
+// в зависимости от Objective. ok = false, если подходящего набора не найдено.
+func Recommend(catalog []ds.Ship, req RecommendationRequest, calc LoadingTimeCalculator) (Recommendation, bool) {
+	maxShips := req.MaxShips
+	if maxShips <= 0 {
+		maxShips = DefaultMaxShips
+	}
+	if maxShips > MaxShipsLimit {
+		maxShips = MaxShipsLimit
+	}
+
+	ships := append([]ds.Ship(nil), catalog...)
+	sort.Slice(ships, func(i, j int) bool { return ships[i].ShipID < ships[j].ShipID })
+
+	// максимальные вместимости среди кораблей с индексом >= i - для отсечения
+	maxTEUFrom := make([]float64, len(ships)+1)
+	maxSlotsFrom := make([]int, len(ships)+1)
+	for i := len(ships) - 1; i >= 0; i-- {
+		maxTEUFrom[i] = maxTEUFrom[i+1]
+		if ships[i].Capacity > maxTEUFrom[i] {
+			maxTEUFrom[i] = ships[i].Capacity
+		}
+		maxSlotsFrom[i] = maxSlotsFrom[i+1]
+		if ships[i].Containers > maxSlotsFrom[i] {
+			maxSlotsFrom[i] = ships[i].Containers
+		}
+	}
+
+	candidate := ds.RequestShip{
+		Containers20ftCount: req.Containers20ft,
+		Containers40ftCount: req.Containers40ft,
+	}
+	demandTEU := float64(candidate.DemandTEU())
+
+	better := func(count int, loadingTime float64, best Recommendation) bool {
+		if best.ShipsCount == 0 {
+			return true
+		}
+		if req.Objective == ObjectiveMinLoadingTime {
+			return loadingTime < best.LoadingTime || (loadingTime == best.LoadingTime && count < best.ShipsCount)
+		}
+		return count < best.ShipsCount || (count == best.ShipsCount && loadingTime < best.LoadingTime)
+	}
+
+	var best Recommendation
+	counts := make([]int, len(ships))
+	budget := recommendSearchBudget
+	truncated := false
+
+	var search func(i, count int, teu float64, slots int)
+	search = func(i, count int, teu float64, slots int) {
+		budget--
+		if budget < 0 {
+			truncated = true
+			return
+		}
+
+		remaining := maxShips - count
+		if teu+float64(remaining)*maxTEUFrom[i] < demandTEU || slots+remaining*maxSlotsFrom[i] < req.Containers40ft {
+			return // даже самые большие корабли не закроют спрос
+		}
+		if req.Objective != ObjectiveMinLoadingTime && best.ShipsCount > 0 && count > best.ShipsCount {
+			return
+		}
+
+		if i == len(ships) {
+			if count == 0 {
+				return
+			}
+			candidate.Ships = candidate.Ships[:0]
+			for j, n := range counts {
+				if n > 0 {
+					candidate.Ships = append(candidate.Ships, ds.ShipInRequest{ShipID: ships[j].ShipID, ShipsCount: n, Ship: ships[j]})
+				}
+			}
+			loadingTime := calc.Calculate(candidate)
+			if req.DeadlineHours > 0 && loadingTime > req.DeadlineHours {
+				return
+			}
+			if better(count, loadingTime, best) {
+				best = Recommendation{
+					Ships:       append([]ds.ShipInRequest(nil), candidate.Ships...),
+					ShipsCount:  count,
+					LoadingTime: loadingTime,
+					CapacityTEU: candidate.FleetCapacityTEU(),
+				}
+			}
+			return
+		}
+
+		for n := 0; n <= remaining && budget >= 0; n++ {
+			counts[i] = n
+			search(i+1, count+n, teu+float64(n)*ships[i].Capacity, slots+n*ships[i].Containers)
+		}
+		counts[i] = 0
+	}
+	search(0, 0, 0, 0)
+
+	best.Truncated = truncated
+	return best, best.ShipsCount > 0
+}
@@ -0,0 +1,244 @@
+package calculator
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+
+	"loading_time/internal/app/ds"
+)
+
+// MaxSimulatedVessels - сколько экземпляров кораблей (с учётом ShipsCount) может быть в симуляции
+const MaxSimulatedVessels = 1000
+
+// ErrSimulationTooLarge - в заявке слишком много кораблей или контейнеров для симуляции
+var ErrSimulationTooLarge = errors.New("request is too large to simulate")
+
+// SimulationResult - итог пошаговой симуляции погрузки заявки
+type SimulationResult struct {
+	Ships          []ShipSimulation `json:"ships"`
+	Makespan       float64          `json:"makespan"`        // время окончания погрузки последнего корабля, ч
+	Unassigned20ft int              `json:"unassigned_20ft"` // не поместились ни на один корабль
+	Unassigned40ft int              `json:"unassigned_40ft"`
+	BerthCranes    int              `json:"berth_cranes"` // береговые краны причала заявки
+}
+
+// ShipSimulation - результат для одного экземпляра корабля заявки
+type ShipSimulation struct {
+	ShipID         int     `json:"ship_id"`
+	Name           string  `json:"name"`
+	Index          int     `json:"index"` // номер экземпляра при ShipsCount > 1, с 1
+	Cranes         int     `json:"cranes"`
+	Containers20ft int     `json:"containers_20ft"`
+	Containers40ft int     `json:"containers_40ft"`
+	FinishTime     float64 `json:"finish_time"` // ч от начала погрузки
+}
+
+// vessel - экземпляр корабля в симуляции; место считается в половинах 40ft слота
+type vessel struct {
+	result    ShipSimulation
+	freeSlots int
+	id        int // индекс в vessels
+	heapIndex int // позиция в vesselQueue, -1 - не в очереди
+}
+
+// vesselQueue - корабли с местом под контейнер текущего размера, первым - тот,
+// что раньше других закончит погрузку (цель для береговых кранов)
+type vesselQueue []*vessel
+
+func (q vesselQueue) Len() int { return len(q) }
+func (q vesselQueue) Less(i, j int) bool {
+	if q[i].result.FinishTime == q[j].result.FinishTime {
+		return q[i].id < q[j].id
+	}
+	return q[i].result.FinishTime < q[j].result.FinishTime
+}
+func (q vesselQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].heapIndex = i
+	q[j].heapIndex = j
+}
+func (q *vesselQueue) Push(x interface{}) {
+	v := x.(*vessel)
+	v.heapIndex = len(*q)
+	*q = append(*q, v)
+}
+func (q *vesselQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	v := old[n-1]
+	v.heapIndex = -1
+	*q = old[:n-1]
+	return v
+}
+
+// shoreCrane - vessel берегового крана: он грузит любой корабль с местом
+const shoreCrane = -1
+
+// crane - кран конкретного корабля (или береговой) и момент, когда он освободится
+type crane struct {
+	vessel int
+	freeAt float64
+}
+
+type craneQueue []*crane
+
+func (q craneQueue) Len() int { return len(q) }
+func (q craneQueue) Less(i, j int) bool {
+	if q[i].freeAt == q[j].freeAt {
+		return q[i].vessel < q[j].vessel
+	}
+	return q[i].freeAt < q[j].freeAt
+}
+func (q craneQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *craneQueue) Push(x interface{}) { *q = append(*q, x.(*crane)) }
+func (q *craneQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	c := old[n-1]
+	*q = old[:n-1]
+	return c
+}
+
+// Simulate - дискретно-событийная симуляция погрузки: каждый контейнер достаётся крану,
+// который освободится раньше всех, при условии что на его корабле есть место.
+// Вместимость корабля - Ship.Containers 40ft контейнеров (20ft занимает половину слота),
+// у каждого корабля работают его собственные Ship.Cranes кранов, а береговые краны причала
+// (Berth.Cranes) берут контейнер для корабля с местом, который раньше других закончит погрузку.
+// Часы на контейнер и КПД крана берутся из параметров стратегии calc.
+// ErrSimulationTooLarge, если кораблей больше MaxSimulatedVessels, контейнеров
+// одного размера больше ds.MaxContainersCount или у корабля либо причала больше ds.MaxCranes кранов.
+func Simulate(requestShip ds.RequestShip, calc LoadingTimeCalculator) (SimulationResult, error) {
+	vesselsCount := 0
+	for _, shipInRequest := range requestShip.Ships {
+		vesselsCount += shipInRequest.ShipsCount
+	}
+	if vesselsCount > MaxSimulatedVessels {
+		return SimulationResult{}, fmt.Errorf("%w: %d ships, at most %d", ErrSimulationTooLarge, vesselsCount, MaxSimulatedVessels)
+	}
+	if requestShip.Containers20ftCount > ds.MaxContainersCount || requestShip.Containers40ftCount > ds.MaxContainersCount {
+		return SimulationResult{}, fmt.Errorf("%w: at most %d containers of each size", ErrSimulationTooLarge, ds.MaxContainersCount)
+	}
+	// на каждый кран в очереди своя запись: их число ограничено так же, как число кораблей
+	for _, shipInRequest := range requestShip.Ships {
+		if shipInRequest.Ship.Cranes > ds.MaxCranes {
+			return SimulationResult{}, fmt.Errorf("%w: ship %d has %d cranes, at most %d",
+				ErrSimulationTooLarge, shipInRequest.ShipID, shipInRequest.Ship.Cranes, ds.MaxCranes)
+		}
+	}
+	if berthCranes(requestShip) > ds.MaxCranes {
+		return SimulationResult{}, fmt.Errorf("%w: berth has %d cranes, at most %d",
+			ErrSimulationTooLarge, berthCranes(requestShip), ds.MaxCranes)
+	}
+
+	params := calc.Params()
+	efficiency := params["crane_efficiency"]
+	if efficiency <= 0 {
+		efficiency = 1
+	}
+	hours20ft := params["hours_20ft"] / efficiency
+	hours40ft := params["hours_40ft"] / efficiency
+
+	vessels := make([]*vessel, 0, vesselsCount)
+	queue := &craneQueue{}
+	for _, shipInRequest := range requestShip.Ships {
+		for i := 1; i <= shipInRequest.ShipsCount; i++ {
+			vessels = append(vessels, &vessel{
+				result: ShipSimulation{
+					ShipID: shipInRequest.ShipID,
+					Name:   shipInRequest.Ship.Name,
+					Index:  i,
+					Cranes: shipInRequest.Ship.Cranes,
+				},
+				freeSlots: shipInRequest.Ship.Containers * 2,
+				id:        len(vessels),
+				heapIndex: -1,
+			})
+			for c := 0; c < shipInRequest.Ship.Cranes; c++ {
+				heap.Push(queue, &crane{vessel: len(vessels) - 1})
+			}
+		}
+	}
+
+	for c := 0; c < berthCranes(requestShip); c++ {
+		heap.Push(queue, &crane{vessel: shoreCrane})
+	}
+
+	result := SimulationResult{BerthCranes: berthCranes(requestShip)}
+
+	// сначала длинные операции (40ft), затем 20ft
+	result.Unassigned40ft = assignContainers(queue, vessels, requestShip.Containers40ftCount, 2, hours40ft)
+	result.Unassigned20ft = assignContainers(queue, vessels, requestShip.Containers20ftCount, 1, hours20ft)
+
+	result.Ships = make([]ShipSimulation, 0, len(vessels))
+	for _, v := range vessels {
+		if v.result.FinishTime > result.Makespan {
+			result.Makespan = v.result.FinishTime
+		}
+		result.Ships = append(result.Ships, v.result)
+	}
+	return result, nil
+}
+
+// assignContainers - раздаёт count контейнеров размером slots освобождающимся кранам,
+// возвращает число контейнеров, которым не нашлось места
+func assignContainers(queue *craneQueue, vessels []*vessel, count, slots int, hours float64) int {
+	open := &vesselQueue{}
+	for _, v := range vessels {
+		if v.freeSlots >= slots {
+			heap.Push(open, v)
+		}
+	}
+
+	// свободное место на кораблях только убывает: кран заполненного корабля (или береговой, когда места
+	// нет нигде) до конца прохода уже не понадобится и возвращается в очередь только после него
+	var parked []*crane
+	assigned := 0
+	for ; assigned < count; assigned++ {
+		var next *crane
+		var target *vessel
+		for queue.Len() > 0 {
+			c := heap.Pop(queue).(*crane)
+			if c.vessel == shoreCrane {
+				if open.Len() > 0 {
+					target = (*open)[0]
+				}
+			} else if vessels[c.vessel].freeSlots >= slots {
+				target = vessels[c.vessel]
+			}
+			if target != nil {
+				next = c
+				break
+			}
+			parked = append(parked, c)
+		}
+		if next == nil {
+			break
+		}
+
+		target.freeSlots -= slots
+		if slots == 2 {
+			target.result.Containers40ft++
+		} else {
+			target.result.Containers20ft++
+		}
+
+		next.freeAt += hours
+		if next.freeAt > target.result.FinishTime {
+			target.result.FinishTime = next.freeAt
+		}
+		heap.Push(queue, next)
+
+		if target.freeSlots < slots {
+			heap.Remove(open, target.heapIndex)
+		} else {
+			heap.Fix(open, target.heapIndex)
+		}
+	}
+
+	// следующему проходу (20ft) краны снова нужны: на корабле может остаться половина слота
+	for _, c := range parked {
+		heap.Push(queue, c)
+	}
+	return count - assigned
+}
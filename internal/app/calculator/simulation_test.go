@@ -0,0 +1,35 @@
+package calculator
+
+import (
+	"errors"
+	"testing"
+
+	"loading_time/internal/app/ds"
+)
+
+func TestSimulateRejectsTooManyCranes(t *testing.T) {
+	ship := ds.ShipInRequest{ShipID: 1, ShipsCount: 1, Ship: ds.Ship{ShipID: 1, Containers: 10, Cranes: 2}}
+	huge := ds.ShipInRequest{ShipID: 2, ShipsCount: 1, Ship: ds.Ship{ShipID: 2, Containers: 10, Cranes: 1e9}}
+
+	tests := []struct {
+		name    string
+		request ds.RequestShip
+		wantErr bool
+	}{
+		{"within limits", ds.RequestShip{Ships: []ds.ShipInRequest{ship}, Berth: &ds.Berth{Cranes: ds.MaxCranes}}, false},
+		{"ship cranes", ds.RequestShip{Ships: []ds.ShipInRequest{ship, huge}}, true},
+		{"berth cranes", ds.RequestShip{Ships: []ds.ShipInRequest{ship}, Berth: &ds.Berth{Cranes: 1e9}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.request.Containers20ftCount = 4
+			_, err := Simulate(tt.request, DefaultCalculator{})
+			if got := errors.Is(err, ErrSimulationTooLarge); got != tt.wantErr {
+				t.Errorf("Simulate: err = %v, want ErrSimulationTooLarge: %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Simulate: %v", err)
+			}
+		})
+	}
+}
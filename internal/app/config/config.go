@@ -1,7 +1,10 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/sirupsen/logrus"
@@ -13,7 +16,63 @@ type Config struct {
 	ServicePort   int
 	RedisEndpoint string
 	RedisPassword string
-	JwtKey        string
+	JWT           JWTConfig
+	SessionStore  SessionStoreConfig
+	Calculator    CalculatorConfig
+	ImageStore    ImageStoreConfig
+	RequestShip   RequestShipConfig
+}
+
+// JWTConfig - токены доступа (auth.TokenService) и срок жизни refresh-токенов, которыми
+// их обновляют (POST /api/users/refresh). Keys - активные ключи подписи по kid:
+// новые токены подписываются SigningKeyID, проверяются все ключи из Keys (ротация).
+// Секреты задаются переменными окружения: JWT_KEY - ключ SigningKeyID,
+// JWT_KEYS="kid:secret,kid:secret" - остальные ключи, например прежний на время ротации.
+type JWTConfig struct {
+	Issuer       string
+	Audience     string
+	TTL          time.Duration
+	RefreshTTL   time.Duration
+	SigningKeyID string
+	Keys         map[string]string
+}
+
+// SessionStoreConfig - хранилище сессий и refresh-токенов: "redis" (по умолчанию,
+// адрес из RedisEndpoint/RedisPassword) или "memory" (в памяти процесса, для разработки и тестов)
+type SessionStoreConfig struct {
+	Driver string
+}
+
+// CalculatorConfig - выбор стратегии расчёта времени погрузки ("default" | "configurable")
+// и параметры для "configurable"
+type CalculatorConfig struct {
+	Strategy           string
+	Hours20ft          float64
+	Hours40ft          float64
+	CraneEfficiency    float64
+	ShiftHours         float64
+	ShiftOverheadHours float64
+}
+
+// ImageStoreConfig - хранилище изображений кораблей: "minio" (по умолчанию) или "local".
+// Для "local" PublicBaseURL - путь, по которому сервер раздаёт LocalDir.
+// PresignExpiry - срок действия временных ссылок на загрузку/скачивание (только "minio").
+type ImageStoreConfig struct {
+	Driver        string
+	Endpoint      string
+	AccessKey     string
+	SecretKey     string
+	UseSSL        bool
+	Bucket        string
+	PublicBaseURL string
+	LocalDir      string
+	PresignExpiry time.Duration
+}
+
+// RequestShipConfig - DeletedRetention: сколько удалённая владельцем заявка хранится
+// до окончательного удаления (модератором или командой purge)
+type RequestShipConfig struct {
+	DeletedRetention time.Duration
 }
 
 func NewConfig() (*Config, error) {
@@ -41,7 +100,8 @@ func NewConfig() (*Config, error) {
 
 	viper.BindEnv("RedisEndpoint", "REDIS_ENDPOINT")
 	viper.BindEnv("RedisPassword", "REDIS_PASSWORD")
-	viper.BindEnv("JwtKey", "JWT_KEY")
+	viper.BindEnv("ImageStore.AccessKey", "MINIO_ACCESS_KEY")
+	viper.BindEnv("ImageStore.SecretKey", "MINIO_SECRET_KEY")
 
 	cfg := &Config{}
 	err = viper.Unmarshal(cfg)
@@ -49,6 +109,33 @@ func NewConfig() (*Config, error) {
 		return nil, err
 	}
 
+	cfg.JWT.Keys, err = jwtKeysFromEnv(cfg.JWT)
+	if err != nil {
+		return nil, err
+	}
+
 	logrus.Info("config parsed")
 	return cfg, nil
 }
+
+// jwtKeysFromEnv - ключи из конфигурации, дополненные JWT_KEYS и JWT_KEY (ключ SigningKeyID)
+func jwtKeysFromEnv(cfg JWTConfig) (map[string]string, error) {
+	keys := map[string]string{}
+	for kid, secret := range cfg.Keys {
+		keys[kid] = secret
+	}
+
+	if list := os.Getenv("JWT_KEYS"); list != "" {
+		for _, pair := range strings.Split(list, ",") {
+			kid, secret, ok := strings.Cut(strings.TrimSpace(pair), ":")
+			if !ok || kid == "" || secret == "" {
+				return nil, fmt.Errorf("JWT_KEYS: expected kid:secret, got %q", pair)
+			}
+			keys[kid] = secret
+		}
+	}
+	if secret := os.Getenv("JWT_KEY"); secret != "" {
+		keys[cfg.SigningKeyID] = secret
+	}
+	return keys, nil
+}
@@ -0,0 +1,89 @@
+package ds
+
+import "fmt"
+
+// @Schema(description="Berth model representing a berth of a terminal with its physical limits")
+type Berth struct {
+	BerthID    int     `gorm:"primaryKey;column:berth_id"`
+	TerminalID int     `gorm:"column:terminal_id;index"`
+	Name       string  `gorm:"column:name"`
+	MaxLength  float64 `gorm:"column:max_length"` // наибольшая длина корабля, м; 0 - без ограничения
+	MaxBeam    float64 `gorm:"column:max_beam"`   // наибольшая ширина (Ship.Width), м; 0 - без ограничения
+	MaxDepth   float64 `gorm:"column:max_depth"`  // глубина у причала - наибольшая осадка (Ship.Draft), м; 0 - без ограничения
+	Cranes     int     `gorm:"column:cranes"`     // береговые краны, работают вместе с кранами кораблей
+}
+
+func (Berth) TableName() string {
+	return "berths"
+}
+
+// Измерения корабля, которые проверяются по ограничениям причала
+const (
+	DimensionLength = "length"
+	DimensionBeam   = "beam"
+	DimensionDraft  = "draft"
+)
+
+// BerthViolation - корабль не проходит по одному из измерений причала
+type BerthViolation struct {
+	ShipID    int
+	ShipName  string
+	Dimension string  // DimensionLength, DimensionBeam или DimensionDraft
+	Value     float64 // размер корабля
+	Limit     float64 // ограничение причала
+}
+
+// Violations - по каким измерениям корабль не помещается у причала (пусто, если помещается)
+func (b Berth) Violations(ship Ship) []BerthViolation {
+	checks := []struct {
+		dimension    string
+		value, limit float64
+	}{
+		{DimensionLength, ship.Length, b.MaxLength},
+		{DimensionBeam, ship.Width, b.MaxBeam},
+		{DimensionDraft, ship.Draft, b.MaxDepth},
+	}
+
+	var violations []BerthViolation
+	for _, check := range checks {
+		if check.limit > 0 && check.value > check.limit {
+			violations = append(violations, BerthViolation{
+				ShipID:    ship.ShipID,
+				ShipName:  ship.Name,
+				Dimension: check.dimension,
+				Value:     check.value,
+				Limit:     check.limit,
+			})
+		}
+	}
+	return violations
+}
+
+// CheckBerthFit возвращает описание нарушений, если корабли заявки не помещаются у выбранного причала.
+// Заявка без причала (или без загруженного Berth) не проверяется.
+func (r RequestShip) CheckBerthFit() *BerthFitError {
+	if r.Berth == nil {
+		return nil
+	}
+
+	var violations []BerthViolation
+	for _, shipInRequest := range r.Ships {
+		violations = append(violations, r.Berth.Violations(shipInRequest.Ship)...)
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	return &BerthFitError{RequestShipID: r.RequestShipID, BerthID: r.Berth.BerthID, Violations: violations}
+}
+
+// BerthFitError - корабли заявки физически не помещаются у причала заявки
+type BerthFitError struct {
+	RequestShipID int
+	BerthID       int
+	Violations    []BerthViolation
+}
+
+func (e *BerthFitError) Error() string {
+	return fmt.Sprintf("request %d: %d ship dimension(s) exceed the limits of berth %d",
+		e.RequestShipID, len(e.Violations), e.BerthID)
+}
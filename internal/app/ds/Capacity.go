@@ -0,0 +1,75 @@
+package ds
+
+import "fmt"
+
+// TEU-спрос и вместимость: 20ft = 1 TEU, 40ft = 2 TEU
+
+// DemandTEU - сколько TEU требуется под контейнеры заявки
+func (r RequestShip) DemandTEU() int {
+	return r.Containers20ftCount + r.Containers40ftCount*2
+}
+
+// FleetCapacityTEU - суммарная вместимость кораблей заявки (Ship.Capacity * ShipsCount)
+func (r RequestShip) FleetCapacityTEU() float64 {
+	capacity := 0.0
+	for _, shipInRequest := range r.Ships {
+		capacity += shipInRequest.Ship.Capacity * float64(shipInRequest.ShipsCount)
+	}
+	return capacity
+}
+
+// Fleet40ftSlots - сколько 40ft контейнеров помещается на корабли заявки (Ship.Containers * ShipsCount)
+func (r RequestShip) Fleet40ftSlots() int {
+	slots := 0
+	for _, shipInRequest := range r.Ships {
+		slots += shipInRequest.Ship.Containers * shipInRequest.ShipsCount
+	}
+	return slots
+}
+
+// CheckCapacity возвращает описание нехватки, если контейнеры не помещаются на выбранные корабли
+func (r RequestShip) CheckCapacity() *CapacityShortfallError {
+	shortfall := &CapacityShortfallError{
+		RequestShipID: r.RequestShipID,
+		DemandTEU:     r.DemandTEU(),
+		CapacityTEU:   r.FleetCapacityTEU(),
+		Demand40ft:    r.Containers40ftCount,
+		Slots40ft:     r.Fleet40ftSlots(),
+	}
+	if shortfall.ShortfallTEU() == 0 && shortfall.Shortfall40ft() == 0 {
+		return nil
+	}
+	return shortfall
+}
+
+// CapacityShortfallError - контейнеры заявки не помещаются на выбранные корабли.
+// Suggestions - корабли каталога, которые закрывают нехватку.
+type CapacityShortfallError struct {
+	RequestShipID int
+	DemandTEU     int
+	CapacityTEU   float64
+	Demand40ft    int
+	Slots40ft     int
+	Suggestions   []Ship
+}
+
+// ShortfallTEU - сколько TEU не хватает (0, если хватает)
+func (e *CapacityShortfallError) ShortfallTEU() float64 {
+	if diff := float64(e.DemandTEU) - e.CapacityTEU; diff > 0 {
+		return diff
+	}
+	return 0
+}
+
+// Shortfall40ft - сколько мест под 40ft контейнеры не хватает (0, если хватает)
+func (e *CapacityShortfallError) Shortfall40ft() int {
+	if diff := e.Demand40ft - e.Slots40ft; diff > 0 {
+		return diff
+	}
+	return 0
+}
+
+func (e *CapacityShortfallError) Error() string {
+	return fmt.Sprintf("request %d exceeds fleet capacity: short of %.0f TEU and %d 40ft slots",
+		e.RequestShipID, e.ShortfallTEU(), e.Shortfall40ft())
+}
@@ -0,0 +1,31 @@
+package ds
+
+import "time"
+
+// RequestShipEventType - вид изменения заявки в истории
+type RequestShipEventType string
+
+const (
+	EventFieldsUpdated    RequestShipEventType = "fields_updated"
+	EventShipAdded        RequestShipEventType = "ship_added"
+	EventShipRemoved      RequestShipEventType = "ship_removed"
+	EventShipCountUpdated RequestShipEventType = "ship_count_updated"
+	EventStatusChanged    RequestShipEventType = "status_changed"
+)
+
+// @Schema(description="RequestShipEvent model representing one change in a request history")
+type RequestShipEvent struct {
+	EventID       int                  `gorm:"primaryKey;column:event_id"`
+	RequestShipID int                  `gorm:"column:request_ship_id;index"`
+	ActorID       int                  `gorm:"column:actor_id"`
+	Actor         User                 `gorm:"foreignKey:ActorID"` // кто внёс изменение
+	EventType     RequestShipEventType `gorm:"column:event_type"`
+	ShipID        *int                 `gorm:"column:ship_id"`   // только для изменений состава кораблей
+	OldValue      string               `gorm:"column:old_value"` // JSON
+	NewValue      string               `gorm:"column:new_value"` // JSON
+	CreatedAt     time.Time            `gorm:"column:created_at"`
+}
+
+func (RequestShipEvent) TableName() string {
+	return "request_ship_events"
+}
@@ -0,0 +1,54 @@
+package ds
+
+import "fmt"
+
+// RequestShipStatus - статус заявки (жизненный цикл RequestShip)
+type RequestShipStatus string
+
+const (
+	StatusDraft     RequestShipStatus = "черновик"
+	StatusFormed    RequestShipStatus = "сформирован"
+	StatusCompleted RequestShipStatus = "завершен"
+	StatusRejected  RequestShipStatus = "отклонен"
+	StatusDeleted   RequestShipStatus = "удалён"
+)
+
+// requestShipTransitions - таблица допустимых переходов.
+// Переход статуса в самого себя означает редактирование полей заявки,
+// поэтому он разрешён только для черновика.
+var requestShipTransitions = map[RequestShipStatus][]RequestShipStatus{
+	StatusDraft:  {StatusDraft, StatusFormed, StatusDeleted},
+	StatusFormed: {StatusCompleted, StatusRejected},
+}
+
+// CanTransitionTo - разрешён ли переход из текущего статуса в next
+func (s RequestShipStatus) CanTransitionTo(next RequestShipStatus) bool {
+	for _, allowed := range requestShipTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckTransition возвращает *IllegalTransitionError, если переход запрещён
+func (s RequestShipStatus) CheckTransition(requestShipID int, next RequestShipStatus) error {
+	if !s.CanTransitionTo(next) {
+		return &IllegalTransitionError{RequestShipID: requestShipID, From: s, To: next}
+	}
+	return nil
+}
+
+// IllegalTransitionError - попытка недопустимого перехода статуса заявки
+type IllegalTransitionError struct {
+	RequestShipID int
+	From          RequestShipStatus
+	To            RequestShipStatus
+}
+
+func (e *IllegalTransitionError) Error() string {
+	if e.From == e.To {
+		return fmt.Sprintf("request %d in status %q cannot be modified", e.RequestShipID, e.From)
+	}
+	return fmt.Sprintf("request %d: illegal status transition %q -> %q", e.RequestShipID, e.From, e.To)
+}
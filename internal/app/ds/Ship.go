@@ -1,5 +1,10 @@
 package ds
 
+import "fmt"
+
+// MaxCranes - верхняя граница числа кранов корабля (Ship.Cranes) и причала (Berth.Cranes)
+const MaxCranes = 100
+
 // @Schema(description="Ship model representing a container ship")
 type Ship struct {
 	ShipID      int     `gorm:"primaryKey;column:ship_id"`
@@ -12,8 +17,37 @@ type Ship struct {
 	Containers  int     `gorm:"column:containers"`
 	Description string  `gorm:"column:description"`
 	PhotoURL    string  `gorm:"column:photo_url"`
+	IsActive    bool    `gorm:"column:is_active;default:true"` // false - корабль удалён из каталога (архив)
+
+	Images ShipImages `gorm:"-"` // ссылки на изображение и его уменьшенные копии, заполняются в обработчиках
+	Match  *ShipMatch `gorm:"-"` // только в результатах полнотекстового поиска
+}
+
+// ShipMatch - релевантность корабля поисковому запросу и фрагменты с подсветкой.
+// Name и Description - безопасный HTML: текст экранирован, совпадения обёрнуты в <mark>.
+type ShipMatch struct {
+	Rank        float64
+	Name        string
+	Description string
+}
+
+// ShipImages - ссылки на оригинал фотографии корабля и копии для списка и карточки
+type ShipImages struct {
+	Original  string
+	Medium    string
+	Thumbnail string
 }
 
 func (Ship) TableName() string {
 	return "ships"
 }
+
+// ShipArchivedError - корабль удалён из каталога: его нельзя добавить в черновик,
+// а черновик с ним нельзя сформировать
+type ShipArchivedError struct {
+	ShipID int
+}
+
+func (e *ShipArchivedError) Error() string {
+	return fmt.Sprintf("ship %d is archived (removed from the catalog)", e.ShipID)
+}
@@ -0,0 +1,20 @@
+package ds
+
+import "time"
+
+// @Schema(description="ShipPhoto model representing one photo in a ship gallery")
+type ShipPhoto struct {
+	PhotoID   int       `gorm:"primaryKey;column:photo_id"`
+	ShipID    int       `gorm:"column:ship_id;index"`
+	FileName  string    `gorm:"column:file_name"` // имя файла в хранилище, как Ship.PhotoURL
+	Caption   string    `gorm:"column:caption"`
+	Position  int       `gorm:"column:position"` // порядок в галерее, с нуля
+	CreatedAt time.Time `gorm:"column:created_at"`
+
+	IsPrimary bool       `gorm:"-"` // совпадает с Ship.PhotoURL
+	Images    ShipImages `gorm:"-"`
+}
+
+func (ShipPhoto) TableName() string {
+	return "ship_photos"
+}
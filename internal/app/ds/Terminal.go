@@ -0,0 +1,13 @@
+package ds
+
+// @Schema(description="Terminal model representing a port terminal with its berths")
+type Terminal struct {
+	TerminalID int     `gorm:"primaryKey;column:terminal_id"`
+	Name       string  `gorm:"column:name"`
+	Port       string  `gorm:"column:port"`
+	Berths     []Berth `gorm:"foreignKey:TerminalID"`
+}
+
+func (Terminal) TableName() string {
+	return "terminals"
+}
@@ -12,8 +12,6 @@ func FromEnv() string {
 	pass := os.Getenv("DB_PASS")
 	dbname := os.Getenv("DB_NAME")
 
-	fmt.Printf("DEBUG: host=%s, port=%s, user=%s, pass=%s, dbname=%s\n", host, port, user, pass, dbname)
-
 	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
 		host, port, user, pass, dbname)
 }
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"loading_time/internal/app/ds"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// GuestCookieName - cookie с токеном гостя, за которым закреплён его черновик
+const GuestCookieName = "guest_draft"
+
+const guestCookieMaxAge = 30 * 24 * 60 * 60 // 30 дней
+
+type guestRepository interface {
+	GetOrCreateGuestUser(guestToken string) (ds.User, error)
+}
+
+// DraftOwnerID - владелец черновика для текущего запроса: пользователь из
+// AuthMiddleware/OptionalAuthMiddleware, иначе гость по cookie (создаётся при необходимости)
+func DraftOwnerID(c *gin.Context, repo guestRepository) (int, error) {
+	if _, exists := c.Get("user_id"); exists {
+		return c.GetInt("user_id"), nil
+	}
+
+	guestToken, err := c.Cookie(GuestCookieName)
+	if err != nil || uuid.Validate(guestToken) != nil {
+		guestToken = uuid.New().String()
+		c.SetCookie(GuestCookieName, guestToken, guestCookieMaxAge, "/", "", false, true)
+	}
+
+	guest, err := repo.GetOrCreateGuestUser(guestToken)
+	if err != nil {
+		return 0, err
+	}
+	return guest.UserID, nil
+}
+
+// ExistingDraftOwnerID - как DraftOwnerID, но для гостя без cookie ничего не создаёт
+// и возвращает ok = false (у такого гостя ещё нет черновика)
+func ExistingDraftOwnerID(c *gin.Context, repo guestRepository) (userID int, ok bool, err error) {
+	if _, exists := c.Get("user_id"); !exists {
+		guestToken, err := c.Cookie(GuestCookieName)
+		if err != nil || uuid.Validate(guestToken) != nil {
+			return 0, false, nil
+		}
+	}
+
+	userID, err = DraftOwnerID(c, repo)
+	if err != nil {
+		return 0, false, err
+	}
+	return userID, true, nil
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"fmt"
+	"loading_time/internal/app/calculator"
+	"loading_time/internal/app/repository"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type RecommendationHandler struct {
+	Repository *repository.Repository
+}
+
+// RecommendFleetAPI - POST /api/recommendations - подбор кораблей под груз
+
+// @Summary Recommend a fleet for a container load
+// @Description Search active catalog ships for the combination with the fewest ships (or the minimum loading time) that fits the containers and meets the deadline; optionally add it to the caller's draft
+// @Tags recommendations
+// @Accept json
+// @Produce json
+// @Param request body object{containers_20ft_count=int,containers_40ft_count=int,deadline_hours=number,max_ships=int,objective=string,apply_to_draft=bool} true "Load, deadline, max_ships (at most 20) and objective (fewest_ships or min_loading_time)"
+// @Success 200 {object} object "data: {ships: []object, ships_count: int, loading_time: number, capacity_teu: number, truncated: bool, request_ship_id: int}"
+// @Failure 400 {object} object "error: string"
+// @Failure 404 {object} object "error: string"
+// @Failure 500 {object} object "error: string"
+// @Router /api/recommendations [post]
+func (h *RecommendationHandler) RecommendFleetAPI(c *gin.Context) {
+	var input struct {
+		Containers20ftCount int     `json:"containers_20ft_count"`
+		Containers40ftCount int     `json:"containers_40ft_count"`
+		DeadlineHours       float64 `json:"deadline_hours"`
+		MaxShips            int     `json:"max_ships"`
+		Objective           string  `json:"objective"`
+		ApplyToDraft        bool    `json:"apply_to_draft"`
+	}
+	if err := c.BindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if input.Containers20ftCount < 0 || input.Containers40ftCount < 0 || input.Containers20ftCount+input.Containers40ftCount == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Container counts must be non-negative and not both zero",
+		})
+		return
+	}
+	if input.DeadlineHours < 0 || input.MaxShips < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "deadline_hours and max_ships must be non-negative",
+		})
+		return
+	}
+	if input.MaxShips > calculator.MaxShipsLimit {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("max_ships must not exceed %d", calculator.MaxShipsLimit),
+		})
+		return
+	}
+	if input.Objective == "" {
+		input.Objective = calculator.ObjectiveFewestShips
+	}
+	if input.Objective != calculator.ObjectiveFewestShips && input.Objective != calculator.ObjectiveMinLoadingTime {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "objective must be 'fewest_ships' or 'min_loading_time'",
+		})
+		return
+	}
+
+	recommendation, ok, err := h.Repository.RecommendFleet(calculator.RecommendationRequest{
+		Containers20ft: input.Containers20ftCount,
+		Containers40ft: input.Containers40ftCount,
+		DeadlineHours:  input.DeadlineHours,
+		MaxShips:       input.MaxShips,
+		Objective:      input.Objective,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if !ok {
+		message := "No combination of catalog ships fits the load and deadline"
+		if recommendation.Truncated {
+			message = "Search limit reached before a fitting combination was found; try a smaller max_ships"
+		}
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":     message,
+			"truncated": recommendation.Truncated,
+		})
+		return
+	}
+
+	ships := []gin.H{}
+	for _, shipInRequest := range recommendation.Ships {
+		ships = append(ships, gin.H{
+			"ship_id":     shipInRequest.ShipID,
+			"name":        shipInRequest.Ship.Name,
+			"capacity":    shipInRequest.Ship.Capacity,
+			"containers":  shipInRequest.Ship.Containers,
+			"cranes":      shipInRequest.Ship.Cranes,
+			"ships_count": shipInRequest.ShipsCount,
+		})
+	}
+	data := gin.H{
+		"ships":        ships,
+		"ships_count":  recommendation.ShipsCount,
+		"loading_time": recommendation.LoadingTime,
+		"capacity_teu": recommendation.CapacityTEU,
+		"truncated":    recommendation.Truncated,
+	}
+
+	// Заполняем черновик вызывающего: весь набор целиком или ничего
+	if input.ApplyToDraft {
+		userID, err := DraftOwnerID(c, h.Repository)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		requestShip, err := h.Repository.GetOrCreateUserDraft(userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		if err := h.Repository.AddShipsToRequestShip(requestShip.RequestShipID, recommendation.Ships, userID); err != nil {
+			logrus.Errorf("RecommendFleetAPI: failed to apply recommendation to request_ship_id=%d: %v", requestShip.RequestShipID, err)
+			writeMutationError(c, err)
+			return
+		}
+		data["request_ship_id"] = requestShip.RequestShipID
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": data,
+	})
+}
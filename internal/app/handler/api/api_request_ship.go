@@ -1,32 +1,175 @@
 package api
 
 import (
+	"encoding/json"
+	"errors"
+	"loading_time/internal/app/calculator"
 	"loading_time/internal/app/ds"
+	"loading_time/internal/app/handler/middleware"
 	"loading_time/internal/app/repository"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 )
 
 type RequestShipHandler struct {
-	Repository *repository.Repository
+	Repository       *repository.Repository
+	DeletedRetention time.Duration // срок хранения удалённых заявок до окончательного удаления
+}
+
+// defaultDeletedRetention - срок хранения, если RequestShip.DeletedRetention не задан в конфигурации
+const defaultDeletedRetention = 30 * 24 * time.Hour
+
+// MutationStatus - HTTP-статус ошибки изменения заявки (общий для API и HTML-обработчиков):
+// 400 для неизвестного причала и количеств вне допустимых пределов, 403 для чужой заявки, 404 для отсутствующей,
+// 409 для недопустимого перехода статуса, удалённого корабля и заявки, которую ещё нельзя удалить
+// окончательно, 422 для нехватки вместимости и кораблей, не помещающихся у причала, иначе 500
+func MutationStatus(err error) int {
+	var transitionErr *ds.IllegalTransitionError
+	var archived *ds.ShipArchivedError
+	var fitErr *ds.BerthFitError
+	var shortfall *ds.CapacityShortfallError
+	switch {
+	case errors.Is(err, repository.ErrBerthNotFound), errors.Is(err, repository.ErrCountLimit):
+		return http.StatusBadRequest
+	case errors.Is(err, repository.ErrNotRequestOwner):
+		return http.StatusForbidden
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, repository.ErrNotPurgeable),
+		errors.As(err, &transitionErr),
+		errors.As(err, &archived):
+		return http.StatusConflict
+	case errors.As(err, &fitErr), errors.As(err, &shortfall):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeMutationError - ошибка изменения заявки со статусом из MutationStatus;
+// для нехватки вместимости, причала и удалённого корабля - с расшифровкой
+func writeMutationError(c *gin.Context, err error) {
+	status := MutationStatus(err)
+
+	var archived *ds.ShipArchivedError
+	var fitErr *ds.BerthFitError
+	var shortfall *ds.CapacityShortfallError
+	switch {
+	case errors.As(err, &fitErr):
+		c.JSON(status, berthFitJSON(fitErr))
+	case errors.As(err, &shortfall):
+		c.JSON(status, capacityShortfallJSON(shortfall))
+	case errors.As(err, &archived):
+		c.JSON(status, gin.H{
+			"error":   err.Error(),
+			"ship_id": archived.ShipID,
+		})
+	default:
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+	}
+}
+
+// capacityShortfallJSON - расшифровка нехватки вместимости и подходящие корабли каталога
+func capacityShortfallJSON(shortfall *ds.CapacityShortfallError) gin.H {
+	suggestions := []gin.H{}
+	for _, ship := range shortfall.Suggestions {
+		suggestions = append(suggestions, gin.H{
+			"ship_id":    ship.ShipID,
+			"name":       ship.Name,
+			"capacity":   ship.Capacity,
+			"containers": ship.Containers,
+			"cranes":     ship.Cranes,
+		})
+	}
+	return gin.H{
+		"error": shortfall.Error(),
+		"shortfall": gin.H{
+			"demand_teu":     shortfall.DemandTEU,
+			"capacity_teu":   shortfall.CapacityTEU,
+			"shortfall_teu":  shortfall.ShortfallTEU(),
+			"demand_40ft":    shortfall.Demand40ft,
+			"slots_40ft":     shortfall.Slots40ft,
+			"shortfall_40ft": shortfall.Shortfall40ft(),
+		},
+		"suggestions": suggestions,
+	}
+}
+
+// berthFitJSON - какие корабли не помещаются у причала и по какому измерению
+func berthFitJSON(fitErr *ds.BerthFitError) gin.H {
+	violations := []gin.H{}
+	for _, violation := range fitErr.Violations {
+		violations = append(violations, gin.H{
+			"ship_id":   violation.ShipID,
+			"name":      violation.ShipName,
+			"dimension": violation.Dimension,
+			"value":     violation.Value,
+			"limit":     violation.Limit,
+		})
+	}
+	return gin.H{
+		"error":      fitErr.Error(),
+		"berth_id":   fitErr.BerthID,
+		"violations": violations,
+	}
+}
+
+// userSummaryJSON - краткие данные пользователя без пароля (nil, если пользователя нет)
+func userSummaryJSON(user *ds.User) gin.H {
+	if user == nil {
+		return nil
+	}
+	return gin.H{
+		"user_id": user.UserID,
+		"fio":     user.FIO,
+		"login":   user.Login,
+	}
+}
+
+// rawJSONOrNull - значение, хранящееся JSON-строкой, отдаём вложенным объектом
+func rawJSONOrNull(value string) json.RawMessage {
+	if value == "" {
+		return json.RawMessage("null")
+	}
+	return json.RawMessage(value)
 }
 
 // GetRequestShipBasketAPI - GET /api/requests/basket - иконка корзины
 
 // @Summary Get request basket
-// @Description Retrieve the count of ships in the user's draft request
+// @Description Retrieve the count of ships in the draft of the authenticated user or of the guest identified by cookie
 // @Tags request_ships
 // @Produce json
 // @Success 200 {object} object "data: {request_ship_id: int, ships_count: int}"
 // @Failure 500 {object} object "error: string"
 // @Router /api/requests/basket [get]
 func (h *RequestShipHandler) GetRequestShipBasketAPI(c *gin.Context) {
-	const fixedUserID = 1
+	userID, ok, err := ExistingDraftOwnerID(c, h.Repository)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if !ok {
+		// у гостя без cookie черновика ещё нет
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{
+				"request_ship_id": 0,
+				"ships_count":     0,
+			},
+		})
+		return
+	}
 
-	requestShip, err := h.Repository.GetOrCreateUserDraft(fixedUserID)
+	requestShip, err := h.Repository.GetOrCreateUserDraft(userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
@@ -50,30 +193,65 @@ func (h *RequestShipHandler) GetRequestShipBasketAPI(c *gin.Context) {
 // GetRequestShipsAPI - GET /api/request_ship - список заявок
 
 // @Summary Get list of shipping requests
-// @Description Retrieve a list of requests with optional filters
+// @Description Retrieve a page of requests with optional filters. Creators see only their own requests, moderators see all.
+// @Description Dates are RFC3339 or YYYY-MM-DD; *_from is inclusive, *_to is inclusive for a date and exclusive for a timestamp.
 // @Tags request_ships
 // @Produce json
-// @Param start_date query string false "Start date filter"
-// @Param end_date query string false "End date filter"
-// @Param status query string false "Status filter"
-// @Success 200 {object} []ds.RequestShip
+// @Param created_from query string false "Creation date from (alias: start_date)"
+// @Param created_to query string false "Creation date to (alias: end_date)"
+// @Param formed_from query string false "Formation date from"
+// @Param formed_to query string false "Formation date to"
+// @Param completed_from query string false "Completion date from"
+// @Param completed_to query string false "Completion date to"
+// @Param status query []string false "Statuses, comma separated or repeated" collectionFormat(multi)
+// @Param user_id query int false "Creator ID (moderators only)"
+// @Param moderator_id query int false "Moderator ID"
+// @Param ship_id query int false "Requests containing this ship"
+// @Param sort query string false "creation_date (default), formation_date, completion_date or loading_time"
+// @Param order query string false "asc or desc (default)"
+// @Param limit query int false "Page size, 20 by default, at most 100"
+// @Param cursor query string false "next_cursor from the previous page"
+// @Success 200 {object} object "data: []ds.RequestShip, total: int, next_cursor: string"
+// @Failure 400 {object} object "error: string"
 // @Failure 500 {object} object "error: string"
 // @Router /api/request_ship [get]
 func (h *RequestShipHandler) GetRequestShipsAPI(c *gin.Context) {
 	// Получаем фильтры из query-параметров
-	startDate := c.Query("start_date")
-	endDate := c.Query("end_date")
-	status := c.Query("status")
+	filter, err := requestShipFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	// Вызываем репозиторий для получения списка заявок
-	requestShips, err := h.Repository.GetRequestShipsFiltered(startDate, endDate, status)
+	// создатель видит только свои заявки (policy.CanAccessRequestShip)
+	if subject, _ := middleware.SubjectFromContext(c); !subject.IsModerator() {
+		filter.UserID = &subject.UserID
+	}
+
+	page, err := h.Repository.ListRequestShips(filter)
+	if errors.Is(err, repository.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Возвращаем JSON
-	c.JSON(http.StatusOK, requestShips)
+	// не отдаём хеши паролей создателя и модератора
+	requestShips := page.Items
+	for i := range requestShips {
+		requestShips[i].User.Password = ""
+		if requestShips[i].Moderator != nil {
+			requestShips[i].Moderator.Password = ""
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":        requestShips,
+		"total":       page.Total,
+		"next_cursor": page.NextCursor,
+	})
 }
 
 // GetRequestShipAPI - GET /api/request_ship/:id - одна заявка с услугами
@@ -83,8 +261,9 @@ func (h *RequestShipHandler) GetRequestShipsAPI(c *gin.Context) {
 // @Tags request_ships
 // @Produce json
 // @Param id path int true "Request ID"
-// @Success 200 {object} object "request_ship_id: int, status: string, creation_date: string, containers_20ft_count: int, containers_40ft_count: int, comment: string, loading_time: int, ships: []object"
+// @Success 200 {object} object "request_ship_id: int, status: string, creation_date: string, formation_date: string, completion_date: string, moderator: {user_id: int, fio: string, login: string}, rejection_reason: string, berth: {berth_id: int, terminal_id: int, name: string, max_length: number, max_beam: number, max_depth: number}, containers_20ft_count: int, containers_40ft_count: int, comment: string, loading_time: int, calculation_strategy: string, calculation_params: object, ships: []object"
 // @Failure 400 {object} object "error: string"
+// @Failure 403 {object} object "error: string (not the owner of the request and not a moderator)"
 // @Failure 404 {object} object "error: string"
 // @Router /api/request_ship/{id} [get]
 func (h *RequestShipHandler) GetRequestShipAPI(c *gin.Context) {
@@ -96,7 +275,7 @@ func (h *RequestShipHandler) GetRequestShipAPI(c *gin.Context) {
 		return
 	}
 
-	requestShip, err := h.Repository.GetRequestShipExcludingDeleted(id)
+	requestShip, err := h.Repository.GetRequestShip(id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error": "Request not found",
@@ -108,10 +287,17 @@ func (h *RequestShipHandler) GetRequestShipAPI(c *gin.Context) {
 		"request_ship_id":       requestShip.RequestShipID,
 		"status":                requestShip.Status,
 		"creation_date":         requestShip.CreationDate,
+		"formation_date":        requestShip.FormationDate,
+		"completion_date":       requestShip.CompletionDate,
+		"moderator":             userSummaryJSON(requestShip.Moderator),
+		"rejection_reason":      requestShip.RejectionReason,
+		"berth":                 berthJSON(requestShip.Berth),
 		"containers_20ft_count": requestShip.Containers20ftCount,
 		"containers_40ft_count": requestShip.Containers40ftCount,
 		"comment":               requestShip.Comment,
 		"loading_time":          requestShip.LoadingTime,
+		"calculation_strategy":  requestShip.CalculationStrategy,
+		"calculation_params":    rawJSONOrNull(requestShip.CalculationParams),
 		"ships": func() []gin.H {
 			ships := []gin.H{}
 			for _, shipInRequest := range requestShip.Ships {
@@ -122,6 +308,7 @@ func (h *RequestShipHandler) GetRequestShipAPI(c *gin.Context) {
 					"capacity":    shipInRequest.Ship.Capacity,
 					"cranes":      shipInRequest.Ship.Cranes,
 					"ships_count": shipInRequest.ShipsCount,
+					"archived":    !shipInRequest.Ship.IsActive,
 				})
 			}
 			return ships
@@ -136,9 +323,11 @@ func (h *RequestShipHandler) GetRequestShipAPI(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path int true "Request ID"
-// @Param request body object{containers_20ft_count=int,containers_40ft_count=int,comment=string} true "Request updates"
+// @Param request body object{containers_20ft_count=int,containers_40ft_count=int,comment=string,berth_id=int} true "Request updates; berth_id is optional: omitted keeps the berth, 0 removes it"
 // @Success 200 {object} object "status: string, message: string"
-// @Failure 400 {object} object "error: string"
+// @Failure 400 {object} object "error: string (also for an unknown berth_id)"
+// @Failure 403 {object} object "error: string (not the owner of the request and not a moderator)"
+// @Failure 409 {object} object "error: string"
 // @Failure 500 {object} object "error: string"
 // @Router /api/request-ships/{id} [put]
 func (h *RequestShipHandler) UpdateRequestShipAPI(c *gin.Context) {
@@ -155,6 +344,7 @@ func (h *RequestShipHandler) UpdateRequestShipAPI(c *gin.Context) {
 		Containers20ftCount int    `json:"containers_20ft_count"`
 		Containers40ftCount int    `json:"containers_40ft_count"`
 		Comment             string `json:"comment"`
+		BerthID             *int   `json:"berth_id"`
 	}
 
 	if err := c.BindJSON(&updates); err != nil {
@@ -166,12 +356,9 @@ func (h *RequestShipHandler) UpdateRequestShipAPI(c *gin.Context) {
 	}
 
 	// Обновляем поля без расчета времени (расчет будет при завершении)
-	err = h.Repository.UpdateRequestShipFields(id, updates.Containers20ftCount, updates.Containers40ftCount, updates.Comment)
+	err = h.Repository.UpdateRequestShipFields(id, updates.Containers20ftCount, updates.Containers40ftCount, updates.Comment, updates.BerthID, c.GetInt("user_id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-
-			"error": err.Error(),
-		})
+		writeMutationError(c, err)
 		return
 	}
 
@@ -196,7 +383,10 @@ func (h *RequestShipHandler) UpdateRequestShipAPI(c *gin.Context) {
 // @Param id path int true "Request ID"
 // @Success 200 {object} object "status: string, message: string"
 // @Failure 400 {object} object "description: string"
+// @Failure 403 {object} object "error: string (not the owner of the request and not a moderator)"
 // @Failure 404 {object} object "description: string"
+// @Failure 409 {object} object "error: string (illegal transition, or archived ship with ship_id: int)"
+// @Failure 422 {object} object "error: string, and either shortfall: object, suggestions: []object or berth_id: int, violations: [{ship_id: int, name: string, dimension: string, value: number, limit: number}]"
 // @Failure 500 {object} object "error: string"
 // @Router /api/request_ship/{id}/formation [put]
 func (h *RequestShipHandler) FormRequestShipAPI(c *gin.Context) {
@@ -209,7 +399,7 @@ func (h *RequestShipHandler) FormRequestShipAPI(c *gin.Context) {
 	}
 
 	// Получаем заявку
-	requestShip, err := h.Repository.GetRequestShipExcludingDeleted(id)
+	requestShip, err := h.Repository.GetRequestShip(id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 
@@ -239,12 +429,9 @@ func (h *RequestShipHandler) FormRequestShipAPI(c *gin.Context) {
 	// УБРАЛИ расчет времени погрузки - только меняем статус
 
 	// меняем статус на "сформирован"
-	err = h.Repository.UpdateRequestShipStatus(id, "сформирован")
+	err = h.Repository.UpdateRequestShipStatus(id, ds.StatusFormed, c.GetInt("user_id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-
-			"error": err.Error(),
-		})
+		writeMutationError(c, err)
 		return
 	}
 
@@ -269,9 +456,13 @@ func (h *RequestShipHandler) FormRequestShipAPI(c *gin.Context) {
 // @Produce json
 // @Param id path int true "Request ID"
 // @Param action formData string true "Action (complete or reject)"
+// @Param rejection_reason formData string false "Reason shown to the creator when the request is rejected"
 // @Success 200 {object} object "status: string, message: string, loading_time: int (if completed)"
 // @Failure 400 {object} object "description: string"
+// @Failure 401 {object} object "description: string"
 // @Failure 404 {object} object "description: string"
+// @Failure 409 {object} object "error: string"
+// @Failure 422 {object} object "error: string, shortfall: object, suggestions: []object"
 // @Failure 500 {object} object "error: string"
 // @Router /api/request-ships/{id}/completion [post]
 func (h *RequestShipHandler) CompleteRequestShipAPI(c *gin.Context) {
@@ -295,7 +486,7 @@ func (h *RequestShipHandler) CompleteRequestShipAPI(c *gin.Context) {
 		return
 	}
 
-	requestShip, err := h.Repository.GetRequestShipExcludingDeleted(id)
+	requestShip, err := h.Repository.GetRequestShip(id)
 	if err != nil {
 		logrus.Errorf("CompleteRequestShipAPI: Request not found for request_ship_id=%d: %v", id, err)
 		c.JSON(http.StatusNotFound, gin.H{
@@ -306,16 +497,24 @@ func (h *RequestShipHandler) CompleteRequestShipAPI(c *gin.Context) {
 	}
 
 	// Проверяем что заявка в статусе "сформирован"
-	if requestShip.Status != "сформирован" {
+	if requestShip.Status != ds.StatusFormed {
 		logrus.Errorf("CompleteRequestShipAPI: Invalid status for request_ship_id=%d: %s", id, requestShip.Status)
-		c.JSON(http.StatusBadRequest, gin.H{
+		c.JSON(http.StatusConflict, gin.H{
 
 			"description": "Only formed requests can be completed or rejected",
 		})
 		return
 	}
 
-	const moderatorID = 1 // Фиксированный модератор
+	// Модератор берётся из JWT (AuthMiddleware)
+	moderatorID := c.GetInt("user_id")
+	if moderatorID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{
+
+			"description": "Moderator identity is missing",
+		})
+		return
+	}
 
 	if action == "complete" {
 		// Рассчитываем время погрузки (бизнес-логика из задания)
@@ -334,13 +533,10 @@ func (h *RequestShipHandler) CompleteRequestShipAPI(c *gin.Context) {
 		}
 
 		// Завершаем заявку с расчетом времени
-		err = h.Repository.CompleteRequestShip(id, moderatorID, "завершен", loadingTime)
+		err = h.Repository.CompleteRequestShip(id, moderatorID, ds.StatusCompleted, loadingTime, "")
 		if err != nil {
 			logrus.Errorf("CompleteRequestShipAPI: Failed to complete request_ship_id=%d: %v", id, err)
-			c.JSON(http.StatusInternalServerError, gin.H{
-
-				"error": err.Error(),
-			})
+			writeMutationError(c, err)
 			return
 		}
 
@@ -351,14 +547,11 @@ func (h *RequestShipHandler) CompleteRequestShipAPI(c *gin.Context) {
 		})
 
 	} else if action == "reject" {
-		// Отклоняем заявку
-		err = h.Repository.CompleteRequestShip(id, moderatorID, "отклонен", 0)
+		// Отклоняем заявку с указанием причины
+		err = h.Repository.CompleteRequestShip(id, moderatorID, ds.StatusRejected, 0, c.PostForm("rejection_reason"))
 		if err != nil {
 			logrus.Errorf("CompleteRequestShipAPI: Failed to reject request_ship_id=%d: %v", id, err)
-			c.JSON(http.StatusInternalServerError, gin.H{
-
-				"error": err.Error(),
-			})
+			writeMutationError(c, err)
 			return
 		}
 
@@ -375,6 +568,54 @@ func (h *RequestShipHandler) CompleteRequestShipAPI(c *gin.Context) {
 	}
 }
 
+// SimulateRequestShipAPI - POST /api/request_ship/:id/simulate - симуляция погрузки по кораблям
+
+// @Summary Simulate request loading
+// @Description Distribute the request containers across its ships respecting each ship's capacity and cranes, plus the shore cranes of the request berth; returns per-ship finish times and the makespan without changing the stored loading time
+// @Tags request_ships
+// @Produce json
+// @Param id path int true "Request ID"
+// @Success 200 {object} object "data: calculator.SimulationResult"
+// @Failure 400 {object} object "error: string"
+// @Failure 403 {object} object "error: string (not the owner of the request and not a moderator)"
+// @Failure 404 {object} object "error: string"
+// @Failure 422 {object} object "error: string (too many ships or containers to simulate)"
+// @Failure 500 {object} object "error: string"
+// @Router /api/request_ship/{id}/simulate [post]
+func (h *RequestShipHandler) SimulateRequestShipAPI(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request ID",
+		})
+		return
+	}
+
+	result, err := h.Repository.SimulateLoading(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Request not found",
+		})
+		return
+	}
+	if errors.Is(err, calculator.ErrSimulationTooLarge) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": result,
+	})
+}
+
 // DeleteShipFromRequestShipAPI - DELETE /api/request_ship/:id/ships/:ship_id - удаление корабля из заявки
 
 // @Summary Delete ship from request
@@ -385,7 +626,10 @@ func (h *RequestShipHandler) CompleteRequestShipAPI(c *gin.Context) {
 // @Param ship_id path int true "Ship ID"
 // @Success 200 {object} object "description: string"
 // @Failure 400 {object} object "status: string, description: string"
-// @Failure 500 {object} object "status: string, description: string"
+// @Failure 403 {object} object "error: string (not the owner of the request and not a moderator)"
+// @Failure 404 {object} object "error: string (request not found)"
+// @Failure 409 {object} object "error: string (request is not a draft)"
+// @Failure 500 {object} object "error: string"
 // @Router /api/request_ship/{id}/ships/{ship_id} [delete]
 func (h *RequestShipHandler) DeleteShipFromRequestShipAPI(c *gin.Context) {
 	requestShipIDStr := c.Param("id")
@@ -404,15 +648,15 @@ func (h *RequestShipHandler) DeleteShipFromRequestShipAPI(c *gin.Context) {
 	}
 
 	// Удаляем корабль из заявки
-	if err := h.Repository.RemoveShipFromRequestShip(requestShipID, shipID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "description": err.Error()})
+	if err := h.Repository.RemoveShipFromRequestShip(requestShipID, shipID, c.GetInt("user_id")); err != nil {
+		writeMutationError(c, err)
 		return
 	}
 
 	// Проверка на запрос от формы
 	if c.PostForm("_method") == "DELETE" {
 		// Получаем обновленную заявку
-		updatedRequestShip, err := h.Repository.GetRequestShipExcludingDeleted(requestShipID)
+		updatedRequestShip, err := h.Repository.GetRequestShip(requestShipID)
 		if err != nil {
 			c.Redirect(http.StatusFound, "/ships")
 			return
@@ -439,9 +683,12 @@ func (h *RequestShipHandler) DeleteShipFromRequestShipAPI(c *gin.Context) {
 // @Produce json
 // @Param id path int true "Request ID"
 // @Param ship_id path int true "Ship ID"
-// @Param request body object{ships_count=int} true "Updated ship count"
+// @Param request body object{ships_count=int} true "Updated ship count (1-100)"
 // @Success 200 {object} object "status: string, message: string"
 // @Failure 400 {object} object "description: string"
+// @Failure 403 {object} object "error: string (not the owner of the request and not a moderator)"
+// @Failure 404 {object} object "error: string (ship is not in the request)"
+// @Failure 409 {object} object "error: string (request is not a draft, or archived ship with ship_id: int)"
 // @Failure 500 {object} object "error: string"
 // @Router /api/request_ship/{id}/ships/{ship_id} [put]
 func (h *RequestShipHandler) UpdateShipInRequestAPI(c *gin.Context) {
@@ -487,12 +734,9 @@ func (h *RequestShipHandler) UpdateShipInRequestAPI(c *gin.Context) {
 	}
 
 	// Обновляем количество кораблей в заявке
-	err = h.Repository.UpdateShipCountInRequest(requestShipID, shipID, input.ShipsCount)
+	err = h.Repository.UpdateShipCountInRequest(requestShipID, shipID, input.ShipsCount, c.GetInt("user_id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-
-			"error": err.Error(),
-		})
+		writeMutationError(c, err)
 		return
 	}
 
@@ -509,15 +753,18 @@ func (h *RequestShipHandler) UpdateShipInRequestAPI(c *gin.Context) {
 	})
 }
 
-// DeleteRequestShipAPI - DELETE /api/request_ship/:id - удаление всей заявки
+// DeleteRequestShipAPI - DELETE /api/request_ship/:id - удаление черновика владельцем
 
-// @Summary Delete a request
-// @Description Remove an entire request from the system
+// @Summary Delete a draft request
+// @Description Soft-delete a draft request of the current user. Deleted requests disappear from lists and are purged by moderators after the retention period
 // @Tags request_ships
 // @Produce json
 // @Param id path int true "Request ID"
 // @Success 200 {object} object "status: string, message: string"
 // @Failure 400 {object} object "message: string"
+// @Failure 403 {object} object "error: string"
+// @Failure 404 {object} object "error: string"
+// @Failure 409 {object} object "error: string"
 // @Failure 500 {object} object "error: string"
 // @Router /api/request_ship/{id} [delete]
 func (h *RequestShipHandler) DeleteRequestShipAPI(c *gin.Context) {
@@ -533,25 +780,16 @@ func (h *RequestShipHandler) DeleteRequestShipAPI(c *gin.Context) {
 
 	logrus.Infof("DeleteRequestShipAPI: Attempting to delete request_ship_id=%d", id)
 
-	// Удаляем зависимые записи
-	err = h.Repository.DB().Delete(&ds.ShipInRequest{}, "request_ship_id = ?", id).Error
-	if err != nil {
-		logrus.Errorf("DeleteRequestShipAPI: Failed to delete ShipInRequest for request_ship_id=%d: %v", id, err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-
-			"error": err.Error(),
+	err = h.Repository.DeleteRequestShipSQL(id, c.GetInt("user_id"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Request not found",
 		})
 		return
 	}
-
-	// Удаляем заявку
-	err = h.Repository.DB().Delete(&ds.RequestShip{}, id).Error
 	if err != nil {
-		logrus.Errorf("DeleteRequestShipAPI: Failed to delete RequestShip for request_ship_id=%d: %v", id, err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-
-			"error": err.Error(),
-		})
+		logrus.Errorf("DeleteRequestShipAPI: Failed to delete request_ship_id=%d: %v", id, err)
+		writeMutationError(c, err)
 		return
 	}
 
@@ -568,3 +806,98 @@ func (h *RequestShipHandler) DeleteRequestShipAPI(c *gin.Context) {
 		"message": "Request ship deleted successfully",
 	})
 }
+
+// PurgeRequestShipAPI - DELETE /api/request_ship/:id/purge - окончательное удаление заявки модератором
+
+// @Summary Purge a deleted request
+// @Description Permanently remove a deleted request with its ships and history once the retention period has passed (moderators only)
+// @Tags request_ships
+// @Produce json
+// @Param id path int true "Request ID"
+// @Success 200 {object} object "status: string, message: string"
+// @Failure 400 {object} object "message: string"
+// @Failure 404 {object} object "error: string"
+// @Failure 409 {object} object "error: string"
+// @Failure 500 {object} object "error: string"
+// @Router /api/request_ship/{id}/purge [delete]
+func (h *RequestShipHandler) PurgeRequestShipAPI(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid request ID",
+		})
+		return
+	}
+
+	retention := h.DeletedRetention
+	if retention <= 0 {
+		retention = defaultDeletedRetention
+	}
+
+	err = h.Repository.PurgeRequestShip(id, retention)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Request not found",
+		})
+		return
+	}
+	if err != nil {
+		logrus.Errorf("PurgeRequestShipAPI: Failed to purge request_ship_id=%d: %v", id, err)
+		writeMutationError(c, err)
+		return
+	}
+
+	logrus.Infof("PurgeRequestShipAPI: request_ship_id=%d purged by user_id=%d", id, c.GetInt("user_id"))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Request ship purged successfully",
+	})
+}
+
+// GetRequestShipHistoryAPI - GET /api/request_ship/:id/history - история изменений заявки
+
+// @Summary Get request history
+// @Description Retrieve the log of changes of a request (moderators only)
+// @Tags request_ships
+// @Produce json
+// @Param id path int true "Request ID"
+// @Success 200 {object} object "count: int, data: []{event_id: int, event_type: string, actor: object, ship_id: int, old_value: object, new_value: object, created_at: string}"
+// @Failure 400 {object} object "error: string"
+// @Failure 500 {object} object "error: string"
+// @Security ApiKeyAuth
+// @Router /api/request_ship/{id}/history [get]
+func (h *RequestShipHandler) GetRequestShipHistoryAPI(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request ID",
+		})
+		return
+	}
+
+	events, err := h.Repository.GetRequestShipEvents(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	data := []gin.H{}
+	for _, event := range events {
+		data = append(data, gin.H{
+			"event_id":   event.EventID,
+			"event_type": event.EventType,
+			"actor":      userSummaryJSON(&event.Actor),
+			"ship_id":    event.ShipID,
+			"old_value":  rawJSONOrNull(event.OldValue),
+			"new_value":  rawJSONOrNull(event.NewValue),
+			"created_at": event.CreatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count": len(data),
+		"data":  data,
+	})
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"fmt"
+	"loading_time/internal/app/ds"
+	"loading_time/internal/app/repository"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestShipFilterFromQuery - фильтр списка заявок из query-параметров GetRequestShipsAPI
+func requestShipFilterFromQuery(c *gin.Context) (repository.RequestShipFilter, error) {
+	var filter repository.RequestShipFilter
+	var err error
+
+	dates := []struct {
+		from, to           string
+		fromPtr, toPtr     **time.Time
+		fromAlias, toAlias string
+	}{
+		{"created_from", "created_to", &filter.CreatedFrom, &filter.CreatedTo, "start_date", "end_date"},
+		{"formed_from", "formed_to", &filter.FormedFrom, &filter.FormedTo, "", ""},
+		{"completed_from", "completed_to", &filter.CompletedFrom, &filter.CompletedTo, "", ""},
+	}
+	for _, d := range dates {
+		if *d.fromPtr, err = queryDate(c, false, d.from, d.fromAlias); err != nil {
+			return filter, err
+		}
+		if *d.toPtr, err = queryDate(c, true, d.to, d.toAlias); err != nil {
+			return filter, err
+		}
+	}
+
+	for _, param := range c.QueryArray("status") {
+		for _, status := range strings.Split(param, ",") {
+			status = strings.TrimSpace(status)
+			if status == "" {
+				continue
+			}
+			if !knownListStatus(ds.RequestShipStatus(status)) {
+				return filter, fmt.Errorf("unknown status %q", status)
+			}
+			filter.Statuses = append(filter.Statuses, ds.RequestShipStatus(status))
+		}
+	}
+
+	if filter.UserID, err = queryInt(c, "user_id"); err != nil {
+		return filter, err
+	}
+	if filter.ModeratorID, err = queryInt(c, "moderator_id"); err != nil {
+		return filter, err
+	}
+	if filter.ShipID, err = queryInt(c, "ship_id"); err != nil {
+		return filter, err
+	}
+
+	filter.SortBy = c.DefaultQuery("sort", repository.SortByCreationDate)
+	switch filter.SortBy {
+	case repository.SortByCreationDate, repository.SortByFormationDate,
+		repository.SortByCompletionDate, repository.SortByLoadingTime:
+	default:
+		return filter, fmt.Errorf("unknown sort field %q", filter.SortBy)
+	}
+
+	switch order := c.DefaultQuery("order", "desc"); order {
+	case "desc":
+		filter.SortDesc = true
+	case "asc":
+	default:
+		return filter, fmt.Errorf("order must be asc or desc")
+	}
+
+	if limit, err := queryInt(c, "limit"); err != nil {
+		return filter, err
+	} else if limit != nil {
+		if *limit <= 0 {
+			return filter, fmt.Errorf("limit must be positive")
+		}
+		filter.Limit = *limit
+	}
+	filter.Cursor = c.Query("cursor")
+
+	return filter, nil
+}
+
+// knownListStatus - статусы, по которым можно фильтровать (удалённые заявки в список не попадают)
+func knownListStatus(status ds.RequestShipStatus) bool {
+	switch status {
+	case ds.StatusDraft, ds.StatusFormed, ds.StatusCompleted, ds.StatusRejected:
+		return true
+	}
+	return false
+}
+
+// queryDate - дата из первого непустого параметра names (RFC3339 или YYYY-MM-DD).
+// Для верхней границы (upper) день включается целиком: возвращается начало следующего дня.
+func queryDate(c *gin.Context, upper bool, names ...string) (*time.Time, error) {
+	for _, name := range names {
+		value := c.Query(name)
+		if name == "" || value == "" {
+			continue
+		}
+
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return &t, nil
+		}
+		t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("%s: expected RFC3339 or YYYY-MM-DD date", name)
+		}
+		if upper {
+			t = t.AddDate(0, 0, 1)
+		}
+		return &t, nil
+	}
+	return nil, nil
+}
+
+// queryInt - целочисленный параметр; nil, если он не задан
+func queryInt(c *gin.Context, name string) (*int, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be an integer", name)
+	}
+	return &n, nil
+}
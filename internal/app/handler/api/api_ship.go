@@ -1,75 +1,235 @@
 package api
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"loading_time/internal/app/ds"
+	"loading_time/internal/app/images"
+	"loading_time/internal/app/repository"
+	"loading_time/internal/app/storage"
 	"net/http"
-	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"github.com/minio/minio-go/v7"
+	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
 
 type ShipHandler struct {
 	Repository interface {
-		GetShips() ([]ds.Ship, error)
-		GetShipsByName(name string) ([]ds.Ship, error)
+		ListShips(filter repository.ShipFilter) (repository.ShipPage, error)
 		GetShip(id int) (ds.Ship, error)
+		GetShipAny(id int) (ds.Ship, error)
 		CreateShip(ship *ds.Ship) error
 		UpdateShip(id int, ship *ds.Ship) error
 		DeleteShip(id int) error
+		RestoreShip(id int) error
+		GetOrCreateUserDraft(userID int) (ds.RequestShip, error)
+		AddShipToRequestShip(requestShipID, shipID, actorID int) error
+		GetOrCreateGuestUser(guestToken string) (ds.User, error)
+		GetShipPhotos(shipID int) ([]ds.ShipPhoto, error)
+		AddShipPhoto(shipID int, fileName, caption string, makePrimary bool) (ds.ShipPhoto, error)
+		UpdateShipPhotoCaption(shipID, photoID int, caption string) error
+		ReorderShipPhotos(shipID int, photoIDs []int) error
+		SetPrimaryShipPhoto(shipID, photoID int) error
+		DeleteShipPhoto(shipID, photoID int) (ds.ShipPhoto, error)
 		DB() *gorm.DB
 	}
-	MinioClient *minio.Client
+	ImageStore    storage.ImageStore
+	PresignExpiry time.Duration // срок действия временных ссылок, см. api_ship_upload.go
+}
+
+// maxShipImageSize - максимальный размер загружаемого изображения
+const maxShipImageSize = 10 << 20 // 10 MB
+
+// shipImageURLs - ссылки на оригинал и копии изображения по Ship.PhotoURL
+func (h *ShipHandler) shipImageURLs(photoURL string) ds.ShipImages {
+	if photoURL == "" || h.ImageStore == nil {
+		return ds.ShipImages{}
+	}
+	return ds.ShipImages{
+		Original:  h.ImageStore.URL(images.ObjectName(photoURL)),
+		Medium:    h.ImageStore.URL(images.ObjectName(images.RenditionName(photoURL, images.RenditionMedium))),
+		Thumbnail: h.ImageStore.URL(images.ObjectName(images.RenditionName(photoURL, images.RenditionThumbnail))),
+	}
+}
+
+// withImageURLs - заполнить Ship.Images для ответа
+func (h *ShipHandler) withImageURLs(ship *ds.Ship) {
+	ship.Images = h.shipImageURLs(ship.PhotoURL)
+}
+
+// uploadShipImage - принять файл из multipart-поля "file" (или "image"), проверить, обработать
+// и загрузить в хранилище вместе с копиями. Возвращает имя файла для Ship.PhotoURL;
+// при ok = false ответ с ошибкой уже отправлен.
+func (h *ShipHandler) uploadShipImage(c *gin.Context, shipID int) (fileName string, ok bool) {
+	// Ограничиваем размер тела запроса, чтобы не читать в память произвольно большие файлы
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxShipImageSize)
+
+	// Парсинг multipart формы
+	if err := c.Request.ParseMultipartForm(maxShipImageSize); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Failed to parse form data",
+		})
+		return "", false
+	}
+
+	// Получаем файл из формы
+	file, _, err := c.Request.FormFile("file")
+	if err != nil {
+		file, _, err = c.Request.FormFile("image")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "No image file provided",
+			})
+			return "", false
+		}
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Failed to read image file",
+		})
+		return "", false
+	}
+
+	return h.storeShipImage(c, shipID, data)
+}
+
+// storeShipImage - проверить и обработать изображение, загрузить оригинал и копии в хранилище.
+// Возвращает имя файла для Ship.PhotoURL; при ok = false ответ с ошибкой уже отправлен.
+func (h *ShipHandler) storeShipImage(c *gin.Context, shipID int, data []byte) (fileName string, ok bool) {
+	// Формат определяется по содержимому, а не по имени файла и Content-Type клиента
+	processed, err := images.Process(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return "", false
+	}
+
+	return h.storeProcessedShipImage(c, shipID, processed)
+}
+
+// storeProcessedShipImage - загрузить обработанное изображение под новым именем;
+// при ok = false ответ с ошибкой уже отправлен
+func (h *ShipHandler) storeProcessedShipImage(c *gin.Context, shipID int, processed images.Processed) (fileName string, ok bool) {
+	// Генерируем уникальное имя файла
+	fileName = uuid.New().String() + processed.Original.Ext
+
+	// Загружаем оригинал и уменьшенные копии
+	if err := h.putShipImage(c.Request.Context(), fileName, processed); err != nil {
+		logrus.Errorf("storeProcessedShipImage: failed to upload %s for ship_id=%d: %v", images.ObjectName(fileName), shipID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to upload image",
+		})
+		return "", false
+	}
+	return fileName, true
+}
+
+// putShipImage - загрузить оригинал и копии; при ошибке уже загруженные объекты удаляются
+func (h *ShipHandler) putShipImage(ctx context.Context, fileName string, processed images.Processed) error {
+	uploaded := make([]string, 0, len(processed.Renditions)+1)
+	put := func(objectName string, file images.File) error {
+		err := h.ImageStore.Put(ctx, objectName, bytes.NewReader(file.Data), int64(len(file.Data)), file.ContentType)
+		if err == nil {
+			uploaded = append(uploaded, objectName)
+		}
+		return err
+	}
+
+	err := put(images.ObjectName(fileName), processed.Original)
+	for _, rendition := range images.Renditions() {
+		if err != nil {
+			break
+		}
+		err = put(images.ObjectName(images.RenditionName(fileName, rendition)), processed.Renditions[rendition])
+	}
+	if err != nil {
+		for _, objectName := range uploaded {
+			if removeErr := h.ImageStore.Remove(ctx, objectName); removeErr != nil {
+				logrus.Warnf("putShipImage: failed to clean up %s: %v", objectName, removeErr)
+			}
+		}
+	}
+	return err
+}
+
+// removeShipImage - удалить оригинал и копии; ошибки только логируются
+func (h *ShipHandler) removeShipImage(ctx context.Context, fileName string) {
+	names := []string{fileName}
+	for _, rendition := range images.Renditions() {
+		names = append(names, images.RenditionName(fileName, rendition))
+	}
+	for _, name := range names {
+		objectName := images.ObjectName(name)
+		if err := h.ImageStore.Remove(ctx, objectName); err != nil {
+			logrus.Warnf("removeShipImage: failed to remove %s: %v", objectName, err)
+		}
+	}
 }
 
 // GetShipsAPI - GET /api/ships - список кораблей с фильтрацией
 
 // @Summary Get list of ships
-// @Description Retrieve a list of ships with optional filters
+// @Description Retrieve a page of the ship catalog with optional filters, multi-field sorting and limit/offset or cursor pagination
 // @Tags ships
 // @Produce json
-// @Param name query string false "Ship name filter"
-// @Param capacity query string false "Minimum capacity filter"
-// @Param is_active query bool false "Active status filter"
-// @Success 200 {object} object "data: []ds.Ship, count: int"
+// @Param q query string false "Full-text search over name and description (Russian and English). Each ship gets match with rank and highlighted snippets"
+// @Param name query string false "Ship name substring filter"
+// @Param capacity query number false "Minimum capacity filter (alias of capacity_min)"
+// @Param capacity_min query number false "Minimum capacity"
+// @Param capacity_max query number false "Maximum capacity"
+// @Param length_min query number false "Minimum length"
+// @Param length_max query number false "Maximum length"
+// @Param width_min query number false "Minimum width"
+// @Param width_max query number false "Maximum width"
+// @Param draft_min query number false "Minimum draft"
+// @Param draft_max query number false "Maximum draft"
+// @Param cranes_min query int false "Minimum cranes"
+// @Param cranes_max query int false "Maximum cranes"
+// @Param containers_min query int false "Minimum 40ft containers"
+// @Param containers_max query int false "Maximum 40ft containers"
+// @Param is_active query bool false "Moderators only: false lists deleted (archived) ships. Others always get active ships"
+// @Param sort query string false "Comma separated fields (name, capacity, length, width, draft, cranes, containers, rank), '-' prefix for descending. rank needs q and is the default with q"
+// @Param limit query int false "Page size, 20 by default, at most 100"
+// @Param offset query int false "Offset (cannot be combined with cursor)"
+// @Param cursor query string false "next_cursor from the previous page"
+// @Success 200 {object} object "data: []ds.Ship, count: int, total: int, next_cursor: string"
+// @Failure 400 {object} object "error: string"
 // @Failure 500 {object} object "error: string"
 // @Router /api/ships [get]
 func (h *ShipHandler) GetShipsAPI(c *gin.Context) {
-	nameFilter := c.Query("name")
-	capacityFilter := c.Query("capacity")
-	isActiveFilter := c.Query("is_active")
-
-	db := h.Repository.DB()
-	query := db.Model(&ds.Ship{})
-
-	if nameFilter != "" {
-		query = query.Where("name ILIKE ?", "%"+nameFilter+"%")
-	}
-	if capacityFilter != "" {
-		if capacity, err := strconv.ParseFloat(capacityFilter, 64); err == nil {
-			query = query.Where("capacity >= ?", capacity)
-		}
-	}
-	if isActiveFilter != "" {
-		isActive := isActiveFilter == "true"
-		query = query.Where("is_active = ?", isActive)
+	filter, err := ShipFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	var ships []ds.Ship
-	if err := query.Find(&ships).Error; err != nil {
+	page, err := h.Repository.ListShips(filter)
+	if errors.Is(err, repository.ErrInvalidCursor) || errors.Is(err, repository.ErrCursorWithOffset) || errors.Is(err, repository.ErrRankWithoutQuery) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"count": len(ships),
-		"data":  ships,
-	})
+	for i := range page.Items {
+		h.withImageURLs(&page.Items[i])
+	}
+
+	c.JSON(http.StatusOK, ShipPageJSON(page))
 }
 
 // GetShipAPI - GET /api/ships/:id - один корабль
@@ -98,6 +258,7 @@ func (h *ShipHandler) GetShipAPI(c *gin.Context) {
 		})
 		return
 	}
+	h.withImageURLs(&ship)
 
 	c.JSON(http.StatusOK, gin.H{
 		"data": ship,
@@ -113,6 +274,7 @@ func (h *ShipHandler) GetShipAPI(c *gin.Context) {
 // @Param ship body ds.Ship true "Ship data"
 // @Success 201 {object} object "data: ds.Ship"
 // @Failure 400 {object} object "error: string"
+// @Failure 403 {object} object "error: string (moderators only)"
 // @Failure 500 {object} object "error: string"
 // @Router /api/ships [post]
 func (h *ShipHandler) CreateShipAPI(c *gin.Context) {
@@ -123,6 +285,9 @@ func (h *ShipHandler) CreateShipAPI(c *gin.Context) {
 		})
 		return
 	}
+	if !validShipCranes(c, ship) {
+		return
+	}
 
 	if err := h.Repository.CreateShip(&ship); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -131,11 +296,24 @@ func (h *ShipHandler) CreateShipAPI(c *gin.Context) {
 		return
 	}
 
+	h.withImageURLs(&ship)
+
 	c.JSON(http.StatusCreated, gin.H{
 		"data": ship,
 	})
 }
 
+// validShipCranes - краны корабля в пределах 0..ds.MaxCranes; false, если ответ с ошибкой уже отправлен
+func validShipCranes(c *gin.Context, ship ds.Ship) bool {
+	if ship.Cranes < 0 || ship.Cranes > ds.MaxCranes {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("cranes must be between 0 and %d", ds.MaxCranes),
+		})
+		return false
+	}
+	return true
+}
+
 // UpdateShipAPI - PUT /api/ships/:id - обновление корабля
 // @Summary Update a ship
 // @Description Update details of an existing ship by ID
@@ -146,6 +324,7 @@ func (h *ShipHandler) CreateShipAPI(c *gin.Context) {
 // @Param ship body ds.Ship true "Updated ship data"
 // @Success 200 {object} object "data: ds.Ship"
 // @Failure 400 {object} object "error: string"
+// @Failure 403 {object} object "error: string (moderators only)"
 // @Failure 500 {object} object "error: string"
 // @Router /api/ships/{id} [put]
 func (h *ShipHandler) UpdateShipAPI(c *gin.Context) {
@@ -164,6 +343,9 @@ func (h *ShipHandler) UpdateShipAPI(c *gin.Context) {
 		})
 		return
 	}
+	if !validShipCranes(c, shipUpdates) {
+		return
+	}
 
 	if err := h.Repository.UpdateShip(id, &shipUpdates); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -173,7 +355,7 @@ func (h *ShipHandler) UpdateShipAPI(c *gin.Context) {
 	}
 
 	// Получаем обновленный корабль для ответа
-	updatedShip, err := h.Repository.GetShip(id)
+	updatedShip, err := h.Repository.GetShipAny(id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
@@ -181,6 +363,8 @@ func (h *ShipHandler) UpdateShipAPI(c *gin.Context) {
 		return
 	}
 
+	h.withImageURLs(&updatedShip)
+
 	c.JSON(http.StatusOK, gin.H{
 		"data": updatedShip,
 	})
@@ -195,6 +379,7 @@ func (h *ShipHandler) UpdateShipAPI(c *gin.Context) {
 // @Param id path int true "Ship ID"
 // @Success 200 {object} object "message: string"
 // @Failure 400 {object} object "message: string"
+// @Failure 403 {object} object "error: string (moderators only)"
 // @Failure 500 {object} object "error: string"
 // @Router /api/ships/{id} [delete]
 func (h *ShipHandler) DeleteShipAPI(c *gin.Context) {
@@ -218,16 +403,65 @@ func (h *ShipHandler) DeleteShipAPI(c *gin.Context) {
 	})
 }
 
+// RestoreShipAPI - PUT /api/ships/:id/restore - вернуть удалённый корабль в каталог
+// @Summary Restore a deleted ship
+// @Description Make a soft-deleted ship active again (moderators only)
+// @Tags ships
+// @Produce json
+// @Param id path int true "Ship ID"
+// @Success 200 {object} object "data: ds.Ship"
+// @Failure 400 {object} object "message: string"
+// @Failure 403 {object} object "error: string (moderators only)"
+// @Failure 404 {object} object "message: string"
+// @Failure 500 {object} object "error: string"
+// @Router /api/ships/{id}/restore [put]
+func (h *ShipHandler) RestoreShipAPI(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid ship ID",
+		})
+		return
+	}
+
+	if err := h.Repository.RestoreShip(id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"message": "Ship not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	ship, err := h.Repository.GetShipAny(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	h.withImageURLs(&ship)
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": ship,
+	})
+}
+
 // AddShipToRequestShipAPI - POST /api/ships/:id/add-to-ship-bucket - добавить корабль в заявку
 
 // @Summary Add ship to request
-// @Description Add a ship to a user's request draft
+// @Description Add a ship to the draft of the authenticated user or of the guest identified by cookie
 // @Tags ships
 // @Produce json
 // @Param id path int true "Ship ID"
 // @Success 200 {object} object "message: string, data: {request_ship_id: int, ship_id: int}"
 // @Failure 400 {object} object "message: string"
 // @Failure 404 {object} object "message: string"
+// @Failure 409 {object} object "error: string (draft already formed, or archived ship with ship_id: int)"
 // @Failure 500 {object} object "status: string, description: string"
 // @Router /api/ships/{id}/add-to-ship-bucket [post]
 func (h *ShipHandler) AddShipToRequestShipAPI(c *gin.Context) {
@@ -243,51 +477,26 @@ func (h *ShipHandler) AddShipToRequestShipAPI(c *gin.Context) {
 		return
 	}
 
-	const fixedUserID = 1
-	db := h.Repository.DB()
+	userID, err := DraftOwnerID(c, h.Repository)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "description": err.Error()})
+		return
+	}
 
 	// Получаем черновик
-	var requestShip ds.RequestShip
-	err = db.Where("status = ? AND user_id = ?", "черновик", fixedUserID).First(&requestShip).Error
+	requestShip, err := h.Repository.GetOrCreateUserDraft(userID)
 	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			requestShip = ds.RequestShip{Status: "черновик", UserID: fixedUserID}
-			if err := db.Create(&requestShip).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "description": err.Error()})
-				return
-			}
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "description": err.Error()})
-			return
-		}
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "description": err.Error()})
+		return
 	}
 
 	// Определяем, JSON-запрос или обычный браузер
 	isJSON := strings.Contains(c.GetHeader("Content-Type"), "application/json") ||
 		strings.Contains(c.GetHeader("Accept"), "application/json")
 
-	// Проверяем, есть ли уже корабль в заявке
-	var existingShip ds.ShipInRequest
-	err = db.Where("request_ship_id = ? AND ship_id = ?", requestShip.RequestShipID, shipID).First(&existingShip).Error
-
-	if err == nil {
-		existingShip.ShipsCount++
-		if err := db.Save(&existingShip).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "description": err.Error()})
-			return
-		}
-	} else if err == gorm.ErrRecordNotFound {
-		newShip := ds.ShipInRequest{
-			RequestShipID: requestShip.RequestShipID,
-			ShipID:        shipID,
-			ShipsCount:    1,
-		}
-		if err := db.Create(&newShip).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "description": err.Error()})
-			return
-		}
-	} else {
-		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "description": err.Error()})
+	// Добавляем корабль (или увеличиваем количество, если он уже в заявке)
+	if err := h.Repository.AddShipToRequestShip(requestShip.RequestShipID, shipID, userID); err != nil {
+		writeMutationError(c, err)
 		return
 	}
 
@@ -314,15 +523,18 @@ func (h *ShipHandler) AddShipToRequestShipAPI(c *gin.Context) {
 
 // AddShipImageAPI - POST /api/ships/:id/image - добавление изображения
 // @Summary Upload ship image
-// @Description Upload an image for a specific ship
+// @Description Upload a JPEG, PNG or WebP image for a specific ship. Metadata (EXIF) is stripped, medium and thumbnail copies are stored alongside the original.
+// @Description The image is added to the ship gallery and becomes the primary photo (photo_url).
 // @Tags ships
 // @Accept multipart/form-data
 // @Produce json
 // @Param id path int true "Ship ID"
 // @Param file formData file true "Image file"
 // @Param image formData file true "Image file (alternative)"
-// @Success 200 {object} object "data: {ship_id: int, photo_url: string, message: string}"
+// @Param caption formData string false "Photo caption"
+// @Success 200 {object} object "data: {ship_id: int, photo_url: string, image_url: string, images: ds.ShipImages, message: string}"
 // @Failure 400 {object} object "message: string"
+// @Failure 403 {object} object "error: string (moderators only)"
 // @Failure 404 {object} object "message: string"
 // @Failure 500 {object} object "message: string"
 // @Router /api/ships/{id}/image [post]
@@ -334,83 +546,34 @@ func (h *ShipHandler) AddShipImageAPI(c *gin.Context) {
 		})
 		return
 	}
-	if h.MinioClient == nil {
+	if h.ImageStore == nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"message": "MinIO client not available",
-		})
-		return
-	}
-
-	// Парсинг multipart формы
-	err = c.Request.ParseMultipartForm(10 << 20) // 10 MB
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"message": "Failed to parse form data",
+			"message": "Image store not available",
 		})
 		return
 	}
 
 	// Проверяем существование корабля
-	ship, err := h.Repository.GetShip(shipID)
-	if err != nil {
+	if _, err := h.Repository.GetShipAny(shipID); err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"message": "Ship not found",
 		})
 		return
 	}
 
-	// Получаем файл из формы
-	file, header, err := c.Request.FormFile("file")
-	if err != nil {
-		file, header, err = c.Request.FormFile("image")
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"message": "No image file provided",
-			})
-			return
-		}
-	}
-	defer file.Close()
-
-	// Генерируем уникальное имя файла
-	fileExt := filepath.Ext(header.Filename)
-	newFileName := uuid.New().String() + fileExt
-
-	// Загружаем в MinIO
-	bucketName := "loading-time-img"
-	objectName := "img/" + newFileName
-
-	_, err = h.MinioClient.PutObject(
-		context.Background(),
-		bucketName,
-		objectName,
-		file,
-		header.Size,
-		minio.PutObjectOptions{
-			ContentType: header.Header.Get("Content-Type"),
-		},
-	)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"message": "Failed to upload image",
-		})
+	newFileName, ok := h.uploadShipImage(c, shipID)
+	if !ok {
 		return
 	}
+	h.savePrimaryShipPhoto(c, shipID, newFileName, c.Request.FormValue("caption"))
+}
 
-	// Удаляем старое изображение
-	if ship.PhotoURL != "" {
-		oldFileName := ship.PhotoURL
-		if strings.Contains(oldFileName, "/") {
-			parts := strings.Split(oldFileName, "/")
-			oldFileName = parts[len(parts)-1]
-		}
-		oldObjectName := "img/" + oldFileName
-		h.MinioClient.RemoveObject(context.Background(), bucketName, oldObjectName, minio.RemoveObjectOptions{})
-	}
-
-	// Сохраняем в БД только имя файла
-	ship.PhotoURL = newFileName
-	if err := h.Repository.UpdateShip(shipID, &ship); err != nil {
+// savePrimaryShipPhoto - добавить загруженное изображение в галерею основным и отправить ответ.
+// Прежние фотографии остаются в галерее.
+func (h *ShipHandler) savePrimaryShipPhoto(c *gin.Context, shipID int, fileName, caption string) {
+	if _, err := h.Repository.AddShipPhoto(shipID, fileName, caption, true); err != nil {
+		logrus.Errorf("savePrimaryShipPhoto: failed to save photo %s for ship_id=%d: %v", fileName, shipID, err)
+		h.removeShipImage(c.Request.Context(), fileName)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"message": "Failed to update ship",
 		})
@@ -420,7 +583,9 @@ func (h *ShipHandler) AddShipImageAPI(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"data": gin.H{
 			"ship_id":   shipID,
-			"photo_url": newFileName,
+			"photo_url": fileName,
+			"image_url": h.ImageStore.URL(images.ObjectName(fileName)),
+			"images":    h.shipImageURLs(fileName),
 			"message":   "Image uploaded successfully",
 		},
 	})
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"fmt"
+	"loading_time/internal/app/handler/middleware"
+	"loading_time/internal/app/repository"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// shipRangeFields - поля каталога с фильтром диапазоном <field>_min / <field>_max
+var shipRangeFields = []string{"capacity", "length", "width", "draft", "cranes", "containers"}
+
+// ShipFilterFromQuery - фильтр каталога из query-параметров; общий для GET /ships и GET /api/ships.
+//
+//	q или search              - полнотекстовый поиск по названию и описанию (результаты с Match)
+//	name                      - подстрока названия
+//	<field>_min, <field>_max  - диапазоны по capacity, length, width, draft, cranes, containers
+//	capacity                  - то же, что capacity_min (совместимость)
+//	is_active=false           - удалённые корабли (только модераторы, иначе игнорируется)
+//	sort=capacity,-length     - сортировка по нескольким полям, "-" - по убыванию;
+//	                            rank - по релевантности (по умолчанию при поиске)
+//	limit, offset, cursor     - страница; cursor - next_cursor предыдущей страницы
+func ShipFilterFromQuery(c *gin.Context) (repository.ShipFilter, error) {
+	filter := repository.ShipFilter{
+		Name:   c.Query("name"),
+		Query:  strings.TrimSpace(c.Query("q")),
+		Ranges: map[string]repository.Range{},
+	}
+	if filter.Query == "" {
+		filter.Query = strings.TrimSpace(c.Query("search"))
+	}
+
+	for _, field := range shipRangeFields {
+		minName := field + "_min"
+		if field == "capacity" && c.Query(minName) == "" {
+			minName = "capacity"
+		}
+		min, err := queryFloat(c, minName)
+		if err != nil {
+			return filter, err
+		}
+		max, err := queryFloat(c, field+"_max")
+		if err != nil {
+			return filter, err
+		}
+		if min != nil || max != nil {
+			filter.Ranges[field] = repository.Range{Min: min, Max: max}
+		}
+	}
+
+	// удалённые корабли видят только модераторы
+	if subject, _ := middleware.SubjectFromContext(c); c.Query("is_active") == "false" && subject.IsModerator() {
+		filter.Inactive = true
+	}
+
+	if sortParam := c.Query("sort"); sortParam != "" {
+		for _, field := range strings.Split(sortParam, ",") {
+			field = strings.TrimSpace(field)
+			sort := repository.ShipSort{Field: strings.TrimPrefix(field, "-"), Desc: strings.HasPrefix(field, "-")}
+			if !repository.IsShipSortField(sort.Field) {
+				return filter, fmt.Errorf("unknown sort field %q", sort.Field)
+			}
+			if sort.Field == repository.ShipSortRank && filter.Query == "" {
+				return filter, repository.ErrRankWithoutQuery
+			}
+			filter.Sort = append(filter.Sort, sort)
+		}
+	}
+
+	limit, err := queryInt(c, "limit")
+	if err != nil {
+		return filter, err
+	}
+	if limit != nil {
+		if *limit <= 0 {
+			return filter, fmt.Errorf("limit must be positive")
+		}
+		filter.Limit = *limit
+	}
+	offset, err := queryInt(c, "offset")
+	if err != nil {
+		return filter, err
+	}
+	if offset != nil {
+		if *offset < 0 {
+			return filter, fmt.Errorf("offset must not be negative")
+		}
+		filter.Offset = *offset
+	}
+	filter.Cursor = c.Query("cursor")
+
+	return filter, nil
+}
+
+// queryFloat - числовой параметр; nil, если он не задан
+func queryFloat(c *gin.Context, name string) (*float64, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a number", name)
+	}
+	return &n, nil
+}
+
+// ShipPageJSON - конверт ответа со страницей каталога
+func ShipPageJSON(page repository.ShipPage) gin.H {
+	return gin.H{
+		"count":       len(page.Items),
+		"total":       page.Total,
+		"next_cursor": page.NextCursor,
+		"data":        page.Items,
+	}
+}
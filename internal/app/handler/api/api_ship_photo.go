@@ -0,0 +1,275 @@
+package api
+
+import (
+	"errors"
+	"loading_time/internal/app/ds"
+	"loading_time/internal/app/repository"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// withPhotoImageURLs - заполнить ShipPhoto.Images для ответа
+func (h *ShipHandler) withPhotoImageURLs(photos []ds.ShipPhoto) {
+	for i := range photos {
+		photos[i].Images = h.shipImageURLs(photos[i].FileName)
+	}
+}
+
+// shipPhotoIDs - разбор :id и :photo_id; при ok = false ответ уже отправлен
+func shipPhotoIDs(c *gin.Context) (shipID, photoID int, ok bool) {
+	shipID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ship ID"})
+		return 0, 0, false
+	}
+	photoID, err = strconv.Atoi(c.Param("photo_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid photo ID"})
+		return 0, 0, false
+	}
+	return shipID, photoID, true
+}
+
+// writeShipPhotoError - 404 для отсутствующего корабля или фотографии, иначе 500
+func writeShipPhotoError(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Photo not found"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// GetShipPhotosAPI - GET /api/ships/:id/photos - галерея корабля
+// @Summary Get ship photos
+// @Description Retrieve the photo gallery of a ship in display order. is_primary marks the photo stored in photo_url
+// @Tags ships
+// @Produce json
+// @Param id path int true "Ship ID"
+// @Success 200 {object} object "data: []ds.ShipPhoto, count: int"
+// @Failure 400 {object} object "message: string"
+// @Failure 404 {object} object "message: string"
+// @Router /api/ships/{id}/photos [get]
+func (h *ShipHandler) GetShipPhotosAPI(c *gin.Context) {
+	shipID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ship ID"})
+		return
+	}
+
+	photos, err := h.Repository.GetShipPhotos(shipID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"message": "Ship not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.withPhotoImageURLs(photos)
+
+	c.JSON(http.StatusOK, gin.H{
+		"count": len(photos),
+		"data":  photos,
+	})
+}
+
+// AddShipPhotoAPI - POST /api/ships/:id/photos - добавить фотографию в галерею
+// @Summary Add ship photo
+// @Description Upload a JPEG, PNG or WebP image to the end of the ship gallery. The first photo of a ship becomes primary automatically
+// @Tags ships
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path int true "Ship ID"
+// @Param file formData file true "Image file"
+// @Param caption formData string false "Photo caption"
+// @Param primary formData bool false "Make the photo primary"
+// @Success 201 {object} object "data: ds.ShipPhoto"
+// @Failure 400 {object} object "message: string"
+// @Failure 403 {object} object "error: string (moderators only)"
+// @Failure 404 {object} object "message: string"
+// @Failure 500 {object} object "message: string"
+// @Router /api/ships/{id}/photos [post]
+func (h *ShipHandler) AddShipPhotoAPI(c *gin.Context) {
+	shipID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ship ID"})
+		return
+	}
+	if h.ImageStore == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Image store not available"})
+		return
+	}
+
+	if _, err := h.Repository.GetShipAny(shipID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Ship not found"})
+		return
+	}
+
+	fileName, ok := h.uploadShipImage(c, shipID)
+	if !ok {
+		return
+	}
+
+	makePrimary := c.Request.FormValue("primary") == "true"
+	photo, err := h.Repository.AddShipPhoto(shipID, fileName, c.Request.FormValue("caption"), makePrimary)
+	if err != nil {
+		logrus.Errorf("AddShipPhotoAPI: failed to save photo %s for ship_id=%d: %v", fileName, shipID, err)
+		h.removeShipImage(c.Request.Context(), fileName)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to save photo"})
+		return
+	}
+	photo.Images = h.shipImageURLs(photo.FileName)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data": photo,
+	})
+}
+
+// UpdateShipPhotoAPI - PUT /api/ships/:id/photos/:photo_id - изменить подпись
+// @Summary Update ship photo caption
+// @Description Change the caption of a photo in the ship gallery
+// @Tags ships
+// @Accept json
+// @Produce json
+// @Param id path int true "Ship ID"
+// @Param photo_id path int true "Photo ID"
+// @Param body body object true "caption: string"
+// @Success 200 {object} object "message: string"
+// @Failure 400 {object} object "message: string"
+// @Failure 403 {object} object "error: string (moderators only)"
+// @Failure 404 {object} object "message: string"
+// @Router /api/ships/{id}/photos/{photo_id} [put]
+func (h *ShipHandler) UpdateShipPhotoAPI(c *gin.Context) {
+	shipID, photoID, ok := shipPhotoIDs(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Caption string `json:"caption"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	if err := h.Repository.UpdateShipPhotoCaption(shipID, photoID, input.Caption); err != nil {
+		writeShipPhotoError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Photo updated successfully"})
+}
+
+// ReorderShipPhotosAPI - PUT /api/ships/:id/photos - новый порядок галереи
+// @Summary Reorder ship photos
+// @Description Set the gallery order. photo_ids must list every photo of the ship exactly once
+// @Tags ships
+// @Accept json
+// @Produce json
+// @Param id path int true "Ship ID"
+// @Param body body object true "photo_ids: []int"
+// @Success 200 {object} object "data: []ds.ShipPhoto, count: int"
+// @Failure 400 {object} object "message: string"
+// @Failure 403 {object} object "error: string (moderators only)"
+// @Failure 500 {object} object "error: string"
+// @Router /api/ships/{id}/photos [put]
+func (h *ShipHandler) ReorderShipPhotosAPI(c *gin.Context) {
+	shipID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ship ID"})
+		return
+	}
+
+	var input struct {
+		PhotoIDs []int `json:"photo_ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	if err := h.Repository.ReorderShipPhotos(shipID, input.PhotoIDs); err != nil {
+		if errors.Is(err, repository.ErrPhotoOrderMismatch) {
+			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+		writeShipPhotoError(c, err)
+		return
+	}
+
+	h.GetShipPhotosAPI(c)
+}
+
+// SetPrimaryShipPhotoAPI - POST /api/ships/:id/photos/:photo_id/primary - сделать фотографию основной
+// @Summary Set primary ship photo
+// @Description Make a gallery photo the primary ship photo (photo_url)
+// @Tags ships
+// @Produce json
+// @Param id path int true "Ship ID"
+// @Param photo_id path int true "Photo ID"
+// @Success 200 {object} object "data: {ship_id: int, photo_url: string}"
+// @Failure 400 {object} object "message: string"
+// @Failure 403 {object} object "error: string (moderators only)"
+// @Failure 404 {object} object "message: string"
+// @Router /api/ships/{id}/photos/{photo_id}/primary [post]
+func (h *ShipHandler) SetPrimaryShipPhotoAPI(c *gin.Context) {
+	shipID, photoID, ok := shipPhotoIDs(c)
+	if !ok {
+		return
+	}
+
+	if err := h.Repository.SetPrimaryShipPhoto(shipID, photoID); err != nil {
+		writeShipPhotoError(c, err)
+		return
+	}
+
+	ship, err := h.Repository.GetShipAny(shipID)
+	if err != nil {
+		writeShipPhotoError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"ship_id":   shipID,
+			"photo_url": ship.PhotoURL,
+			"images":    h.shipImageURLs(ship.PhotoURL),
+		},
+	})
+}
+
+// DeleteShipPhotoAPI - DELETE /api/ships/:id/photos/:photo_id - удалить фотографию
+// @Summary Delete ship photo
+// @Description Remove a photo from the gallery and the object store. If it was primary, the next photo in order becomes primary
+// @Tags ships
+// @Produce json
+// @Param id path int true "Ship ID"
+// @Param photo_id path int true "Photo ID"
+// @Success 200 {object} object "message: string"
+// @Failure 400 {object} object "message: string"
+// @Failure 403 {object} object "error: string (moderators only)"
+// @Failure 404 {object} object "message: string"
+// @Router /api/ships/{id}/photos/{photo_id} [delete]
+func (h *ShipHandler) DeleteShipPhotoAPI(c *gin.Context) {
+	shipID, photoID, ok := shipPhotoIDs(c)
+	if !ok {
+		return
+	}
+
+	photo, err := h.Repository.DeleteShipPhoto(shipID, photoID)
+	if err != nil {
+		writeShipPhotoError(c, err)
+		return
+	}
+
+	if h.ImageStore != nil {
+		h.removeShipImage(c.Request.Context(), photo.FileName)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Photo deleted successfully"})
+}
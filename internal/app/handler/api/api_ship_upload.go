@@ -0,0 +1,242 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"loading_time/internal/app/images"
+	"loading_time/internal/app/storage"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// Прямая загрузка изображений в хранилище в обход API-сервера:
+// 1. POST /api/ships/:id/image/upload-url - временная POST-форма на объект img/pending/<ship_id>/<upload_id>;
+//    размер (до maxDirectUploadSize) и тип (image/*) проверяет само хранилище по политике формы;
+// 2. клиент загружает файл по форме;
+// 3. POST /api/ships/:id/image/confirm - сервер потоком читает загруженный объект, перекодирует
+//    оригинал без метаданных, сохраняет копии и удаляет временный объект.
+// Неподтверждённые загрузки остаются в img/pending/ и удаляются сборщиком мусора.
+
+// defaultPresignExpiry - срок действия ссылок, если PresignExpiry не задан в конфигурации
+const defaultPresignExpiry = 15 * time.Minute
+
+// maxDirectUploadSize - максимальный размер файла, загружаемого напрямую в хранилище
+const maxDirectUploadSize = 50 << 20 // 50 MB
+
+// pendingUploadObjectName - временный объект для прямой загрузки изображения корабля
+func pendingUploadObjectName(shipID int, uploadID string) string {
+	return images.ObjectPrefix + "pending/" + strconv.Itoa(shipID) + "/" + uploadID
+}
+
+// presigner - хранилище с поддержкой временных ссылок; при ok = false ответ уже отправлен
+func (h *ShipHandler) presigner(c *gin.Context) (storage.Presigner, time.Duration, bool) {
+	presigner, ok := h.ImageStore.(storage.Presigner)
+	if !ok {
+		c.JSON(http.StatusNotImplemented, gin.H{
+			"message": "Image store does not support pre-signed URLs",
+		})
+		return nil, 0, false
+	}
+
+	expiry := h.PresignExpiry
+	if expiry <= 0 {
+		expiry = defaultPresignExpiry
+	}
+	return presigner, expiry, true
+}
+
+// CreateShipImageUploadURLAPI - POST /api/ships/:id/image/upload-url - ссылка для прямой загрузки
+// @Summary Get pre-signed upload URL for ship image
+// @Description Returns a short-lived pre-signed POST form. Send the returned fields, a Content-Type field with the image type (image/*) and then the image as "file" (multipart/form-data, up to 50 MB) to upload_url, then call /api/ships/{id}/image/confirm with the upload_id
+// @Tags ships
+// @Produce json
+// @Param id path int true "Ship ID"
+// @Success 200 {object} object "data: {upload_id: string, upload_url: string, method: string, fields: object, expires_at: string, max_size: int}"
+// @Failure 400 {object} object "message: string"
+// @Failure 403 {object} object "error: string (moderators only)"
+// @Failure 404 {object} object "message: string"
+// @Failure 501 {object} object "message: string"
+// @Router /api/ships/{id}/image/upload-url [post]
+func (h *ShipHandler) CreateShipImageUploadURLAPI(c *gin.Context) {
+	shipID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ship ID"})
+		return
+	}
+
+	presigner, expiry, ok := h.presigner(c)
+	if !ok {
+		return
+	}
+
+	if _, err := h.Repository.GetShipAny(shipID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Ship not found"})
+		return
+	}
+
+	uploadID := uuid.New().String()
+	uploadURL, fields, err := presigner.PresignPost(c.Request.Context(), pendingUploadObjectName(shipID, uploadID), maxDirectUploadSize, "image/", expiry)
+	if err != nil {
+		logrus.Errorf("CreateShipImageUploadURLAPI: failed to presign upload for ship_id=%d: %v", shipID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to create upload URL"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"upload_id":  uploadID,
+			"upload_url": uploadURL,
+			"method":     http.MethodPost,
+			"fields":     fields,
+			"expires_at": time.Now().Add(expiry),
+			"max_size":   maxDirectUploadSize,
+		},
+	})
+}
+
+// ConfirmShipImageUploadAPI - POST /api/ships/:id/image/confirm - подтвердить прямую загрузку
+// @Summary Confirm pre-signed ship image upload
+// @Description Validates the uploaded object (JPEG, PNG or WebP, up to 50 MB), re-encodes it without metadata, stores medium and thumbnail copies and makes the image the primary ship photo
+// @Tags ships
+// @Accept json
+// @Produce json
+// @Param id path int true "Ship ID"
+// @Param body body object true "upload_id: string, caption: string"
+// @Success 200 {object} object "data: {ship_id: int, photo_url: string, image_url: string, images: ds.ShipImages, message: string}"
+// @Failure 400 {object} object "message: string"
+// @Failure 403 {object} object "error: string (moderators only)"
+// @Failure 404 {object} object "message: string"
+// @Failure 413 {object} object "message: string"
+// @Failure 500 {object} object "message: string"
+// @Router /api/ships/{id}/image/confirm [post]
+func (h *ShipHandler) ConfirmShipImageUploadAPI(c *gin.Context) {
+	shipID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ship ID"})
+		return
+	}
+	if h.ImageStore == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Image store not available"})
+		return
+	}
+
+	var input struct {
+		UploadID string `json:"upload_id" binding:"required"`
+		Caption  string `json:"caption"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	// upload_id входит в имя объекта - принимаем только выданные нами UUID
+	if uuid.Validate(input.UploadID) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid upload ID"})
+		return
+	}
+
+	if _, err := h.Repository.GetShipAny(shipID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Ship not found"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	pendingName := pendingUploadObjectName(shipID, strings.ToLower(input.UploadID))
+	object, size, err := h.ImageStore.Open(ctx, pendingName)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"message": "Uploaded image not found"})
+			return
+		}
+		logrus.Errorf("ConfirmShipImageUploadAPI: failed to open %s: %v", pendingName, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to read uploaded image"})
+		return
+	}
+
+	// Временный объект больше не нужен независимо от результата проверки
+	defer func() {
+		if err := h.ImageStore.Remove(ctx, pendingName); err != nil {
+			logrus.Warnf("ConfirmShipImageUploadAPI: failed to remove %s: %v", pendingName, err)
+		}
+	}()
+
+	defer object.Close()
+
+	// размер ограничен политикой формы; проверяем по метаданным объекта ещё до чтения
+	if size > maxDirectUploadSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"message": "Image is too large"})
+		return
+	}
+
+	processed, err := images.ProcessReader(io.LimitReader(object, maxDirectUploadSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	fileName, ok := h.storeProcessedShipImage(c, shipID, processed)
+	if !ok {
+		return
+	}
+	h.savePrimaryShipPhoto(c, shipID, fileName, input.Caption)
+}
+
+// GetShipImageDownloadURLAPI - GET /api/ships/:id/image/download-url - временная ссылка на изображение
+// @Summary Get pre-signed download URL for ship image
+// @Description Returns a short-lived pre-signed GET URL for the primary ship photo or one of its copies
+// @Tags ships
+// @Produce json
+// @Param id path int true "Ship ID"
+// @Param rendition query string false "Image size: medium or thumb (original by default)"
+// @Success 200 {object} object "data: {download_url: string, expires_at: string}"
+// @Failure 400 {object} object "message: string"
+// @Failure 404 {object} object "message: string"
+// @Failure 501 {object} object "message: string"
+// @Router /api/ships/{id}/image/download-url [get]
+func (h *ShipHandler) GetShipImageDownloadURLAPI(c *gin.Context) {
+	shipID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ship ID"})
+		return
+	}
+
+	rendition := c.Query("rendition")
+	if rendition != images.RenditionOriginal && rendition != images.RenditionMedium && rendition != images.RenditionThumbnail {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Unknown rendition"})
+		return
+	}
+
+	presigner, expiry, ok := h.presigner(c)
+	if !ok {
+		return
+	}
+
+	ship, err := h.Repository.GetShip(shipID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Ship not found"})
+		return
+	}
+	if ship.PhotoURL == "" {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Ship has no image"})
+		return
+	}
+
+	objectName := images.ObjectName(images.RenditionName(ship.PhotoURL, rendition))
+	downloadURL, err := presigner.PresignGet(c.Request.Context(), objectName, expiry)
+	if err != nil {
+		logrus.Errorf("GetShipImageDownloadURLAPI: failed to presign %s: %v", objectName, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to create download URL"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"download_url": downloadURL,
+			"expires_at":   time.Now().Add(expiry),
+		},
+	})
+}
@@ -0,0 +1,439 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"loading_time/internal/app/ds"
+	"loading_time/internal/app/repository"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type TerminalHandler struct {
+	Repository *repository.Repository
+}
+
+// berthJSON - причал и его ограничения (nil, если причал не выбран)
+func berthJSON(berth *ds.Berth) gin.H {
+	if berth == nil {
+		return nil
+	}
+	return gin.H{
+		"berth_id":    berth.BerthID,
+		"terminal_id": berth.TerminalID,
+		"name":        berth.Name,
+		"max_length":  berth.MaxLength,
+		"max_beam":    berth.MaxBeam,
+		"max_depth":   berth.MaxDepth,
+		"cranes":      berth.Cranes,
+	}
+}
+
+// terminalJSON - терминал вместе с причалами
+func terminalJSON(terminal ds.Terminal) gin.H {
+	berths := []gin.H{}
+	for i := range terminal.Berths {
+		berths = append(berths, berthJSON(&terminal.Berths[i]))
+	}
+	return gin.H{
+		"terminal_id": terminal.TerminalID,
+		"name":        terminal.Name,
+		"port":        terminal.Port,
+		"berths":      berths,
+	}
+}
+
+// writeTerminalError - отсутствующий терминал или причал как 404,
+// удаление используемого терминала или причала как 409, остальное как 500
+func writeTerminalError(c *gin.Context, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, repository.ErrBerthNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, repository.ErrTerminalHasBerths), errors.Is(err, repository.ErrBerthInUse):
+		code = http.StatusConflict
+	default:
+		logrus.Error(err)
+	}
+	c.JSON(code, gin.H{
+		"error": err.Error(),
+	})
+}
+
+// terminalInput - поля терминала в теле запроса
+type terminalInput struct {
+	Name string `json:"name"`
+	Port string `json:"port"`
+}
+
+// bindTerminal - терминал из тела запроса; false, если ответ с ошибкой уже отправлен
+func bindTerminal(c *gin.Context) (ds.Terminal, bool) {
+	var input terminalInput
+	if err := c.BindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return ds.Terminal{}, false
+	}
+	if strings.TrimSpace(input.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "name is required",
+		})
+		return ds.Terminal{}, false
+	}
+	return ds.Terminal{Name: strings.TrimSpace(input.Name), Port: strings.TrimSpace(input.Port)}, true
+}
+
+// berthInput - поля причала в теле запроса; 0 в ограничениях - без ограничения
+type berthInput struct {
+	Name      string  `json:"name"`
+	MaxLength float64 `json:"max_length"`
+	MaxBeam   float64 `json:"max_beam"`
+	MaxDepth  float64 `json:"max_depth"`
+	Cranes    int     `json:"cranes"`
+}
+
+// bindBerth - причал из тела запроса; false, если ответ с ошибкой уже отправлен
+func bindBerth(c *gin.Context) (ds.Berth, bool) {
+	var input berthInput
+	if err := c.BindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return ds.Berth{}, false
+	}
+	if strings.TrimSpace(input.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "name is required",
+		})
+		return ds.Berth{}, false
+	}
+	if input.MaxLength < 0 || input.MaxBeam < 0 || input.MaxDepth < 0 || input.Cranes < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "max_length, max_beam, max_depth and cranes must be non-negative",
+		})
+		return ds.Berth{}, false
+	}
+	if input.Cranes > ds.MaxCranes {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("cranes must be at most %d", ds.MaxCranes),
+		})
+		return ds.Berth{}, false
+	}
+	return ds.Berth{
+		Name:      strings.TrimSpace(input.Name),
+		MaxLength: input.MaxLength,
+		MaxBeam:   input.MaxBeam,
+		MaxDepth:  input.MaxDepth,
+		Cranes:    input.Cranes,
+	}, true
+}
+
+// pathID - числовой параметр пути; false, если ответ с ошибкой уже отправлен
+func pathID(c *gin.Context, name string) (int, bool) {
+	id, err := strconv.Atoi(c.Param(name))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid " + name,
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+// GetTerminalsAPI - GET /api/terminals - терминалы и причалы, которые можно указать в заявке
+
+// @Summary Get terminals
+// @Description Retrieve terminals with their berths, berth limits (max length, beam and depth; 0 means no limit) and shore cranes
+// @Tags terminals
+// @Produce json
+// @Success 200 {object} object "data: [{terminal_id: int, name: string, port: string, berths: []object}]"
+// @Failure 500 {object} object "error: string"
+// @Router /api/terminals [get]
+func (h *TerminalHandler) GetTerminalsAPI(c *gin.Context) {
+	terminals, err := h.Repository.GetTerminals()
+	if err != nil {
+		logrus.Errorf("Ошибка получения терминалов: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	data := []gin.H{}
+	for _, terminal := range terminals {
+		data = append(data, terminalJSON(terminal))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": data,
+	})
+}
+
+// GetTerminalAPI - GET /api/terminals/:id - терминал с причалами
+
+// @Summary Get a terminal
+// @Description Retrieve a terminal with its berths
+// @Tags terminals
+// @Produce json
+// @Param id path int true "Terminal ID"
+// @Success 200 {object} object "data: {terminal_id: int, name: string, port: string, berths: []object}"
+// @Failure 400 {object} object "error: string"
+// @Failure 404 {object} object "error: string"
+// @Router /api/terminals/{id} [get]
+func (h *TerminalHandler) GetTerminalAPI(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	terminal, err := h.Repository.GetTerminal(id)
+	if err != nil {
+		writeTerminalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": terminalJSON(terminal),
+	})
+}
+
+// CreateTerminalAPI - POST /api/terminals - создание терминала (модератор)
+
+// @Summary Create a terminal
+// @Description Add a terminal; berths are added separately
+// @Tags terminals
+// @Accept json
+// @Produce json
+// @Param terminal body object{name=string,port=string} true "Terminal data"
+// @Success 201 {object} object "data: {terminal_id: int, name: string, port: string, berths: []object}"
+// @Failure 400 {object} object "error: string"
+// @Failure 403 {object} object "error: string (moderators only)"
+// @Failure 500 {object} object "error: string"
+// @Router /api/terminals [post]
+func (h *TerminalHandler) CreateTerminalAPI(c *gin.Context) {
+	terminal, ok := bindTerminal(c)
+	if !ok {
+		return
+	}
+
+	if err := h.Repository.CreateTerminal(&terminal); err != nil {
+		writeTerminalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data": terminalJSON(terminal),
+	})
+}
+
+// UpdateTerminalAPI - PUT /api/terminals/:id - изменение терминала (модератор)
+
+// @Summary Update a terminal
+// @Description Replace the name and port of a terminal
+// @Tags terminals
+// @Accept json
+// @Produce json
+// @Param id path int true "Terminal ID"
+// @Param terminal body object{name=string,port=string} true "Terminal data"
+// @Success 200 {object} object "data: {terminal_id: int, name: string, port: string, berths: []object}"
+// @Failure 400 {object} object "error: string"
+// @Failure 403 {object} object "error: string (moderators only)"
+// @Failure 404 {object} object "error: string"
+// @Failure 500 {object} object "error: string"
+// @Router /api/terminals/{id} [put]
+func (h *TerminalHandler) UpdateTerminalAPI(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+	terminal, ok := bindTerminal(c)
+	if !ok {
+		return
+	}
+
+	if err := h.Repository.UpdateTerminal(id, terminal); err != nil {
+		writeTerminalError(c, err)
+		return
+	}
+
+	updated, err := h.Repository.GetTerminal(id)
+	if err != nil {
+		writeTerminalError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data": terminalJSON(updated),
+	})
+}
+
+// DeleteTerminalAPI - DELETE /api/terminals/:id - удаление терминала без причалов (модератор)
+
+// @Summary Delete a terminal
+// @Description Delete a terminal that has no berths left
+// @Tags terminals
+// @Produce json
+// @Param id path int true "Terminal ID"
+// @Success 200 {object} object "status: string, message: string"
+// @Failure 400 {object} object "error: string"
+// @Failure 403 {object} object "error: string (moderators only)"
+// @Failure 404 {object} object "error: string"
+// @Failure 409 {object} object "error: string (terminal still has berths)"
+// @Failure 500 {object} object "error: string"
+// @Router /api/terminals/{id} [delete]
+func (h *TerminalHandler) DeleteTerminalAPI(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.Repository.DeleteTerminal(id); err != nil {
+		writeTerminalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Terminal deleted successfully",
+	})
+}
+
+// GetBerthAPI - GET /api/berths/:id - причал
+
+// @Summary Get a berth
+// @Description Retrieve a berth with its limits and shore cranes
+// @Tags terminals
+// @Produce json
+// @Param id path int true "Berth ID"
+// @Success 200 {object} object "data: {berth_id: int, terminal_id: int, name: string, max_length: number, max_beam: number, max_depth: number, cranes: int}"
+// @Failure 400 {object} object "error: string"
+// @Failure 404 {object} object "error: string"
+// @Router /api/berths/{id} [get]
+func (h *TerminalHandler) GetBerthAPI(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	berth, err := h.Repository.GetBerth(id)
+	if err != nil {
+		writeTerminalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": berthJSON(&berth),
+	})
+}
+
+// CreateBerthAPI - POST /api/terminals/:id/berths - новый причал терминала (модератор)
+
+// @Summary Create a berth
+// @Description Add a berth to a terminal. Limits of 0 mean no limit; cranes are shore cranes that work alongside ship cranes
+// @Tags terminals
+// @Accept json
+// @Produce json
+// @Param id path int true "Terminal ID"
+// @Param berth body object{name=string,max_length=number,max_beam=number,max_depth=number,cranes=int} true "Berth data"
+// @Success 201 {object} object "data: {berth_id: int, terminal_id: int, name: string, max_length: number, max_beam: number, max_depth: number, cranes: int}"
+// @Failure 400 {object} object "error: string"
+// @Failure 403 {object} object "error: string (moderators only)"
+// @Failure 404 {object} object "error: string"
+// @Failure 500 {object} object "error: string"
+// @Router /api/terminals/{id}/berths [post]
+func (h *TerminalHandler) CreateBerthAPI(c *gin.Context) {
+	terminalID, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+	berth, ok := bindBerth(c)
+	if !ok {
+		return
+	}
+	berth.TerminalID = terminalID
+
+	if err := h.Repository.CreateBerth(&berth); err != nil {
+		writeTerminalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data": berthJSON(&berth),
+	})
+}
+
+// UpdateBerthAPI - PUT /api/berths/:id - изменение причала (модератор)
+
+// @Summary Update a berth
+// @Description Replace the name, limits and shore cranes of a berth. Formed requests are not re-checked
+// @Tags terminals
+// @Accept json
+// @Produce json
+// @Param id path int true "Berth ID"
+// @Param berth body object{name=string,max_length=number,max_beam=number,max_depth=number,cranes=int} true "Berth data"
+// @Success 200 {object} object "data: {berth_id: int, terminal_id: int, name: string, max_length: number, max_beam: number, max_depth: number, cranes: int}"
+// @Failure 400 {object} object "error: string"
+// @Failure 403 {object} object "error: string (moderators only)"
+// @Failure 404 {object} object "error: string"
+// @Failure 500 {object} object "error: string"
+// @Router /api/berths/{id} [put]
+func (h *TerminalHandler) UpdateBerthAPI(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+	berth, ok := bindBerth(c)
+	if !ok {
+		return
+	}
+
+	if err := h.Repository.UpdateBerth(id, berth); err != nil {
+		writeTerminalError(c, err)
+		return
+	}
+
+	updated, err := h.Repository.GetBerth(id)
+	if err != nil {
+		writeTerminalError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data": berthJSON(&updated),
+	})
+}
+
+// DeleteBerthAPI - DELETE /api/berths/:id - удаление причала без заявок (модератор)
+
+// @Summary Delete a berth
+// @Description Delete a berth that no request refers to
+// @Tags terminals
+// @Produce json
+// @Param id path int true "Berth ID"
+// @Success 200 {object} object "status: string, message: string"
+// @Failure 400 {object} object "error: string"
+// @Failure 403 {object} object "error: string (moderators only)"
+// @Failure 404 {object} object "error: string"
+// @Failure 409 {object} object "error: string (berth is referenced by requests)"
+// @Failure 500 {object} object "error: string"
+// @Router /api/berths/{id} [delete]
+func (h *TerminalHandler) DeleteBerthAPI(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.Repository.DeleteBerth(id); err != nil {
+		writeTerminalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Berth deleted successfully",
+	})
+}
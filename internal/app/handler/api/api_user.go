@@ -1,84 +1,67 @@
 package api
 
 import (
-	"fmt"
+	"errors"
 	"net/http"
-	"strings"
 	"time"
 
+	"loading_time/internal/app/auth"
 	"loading_time/internal/app/ds"
+	"loading_time/internal/app/handler/middleware"
+	"loading_time/internal/app/policy"
 	"loading_time/internal/app/repository"
-	"loading_time/internal/app/utils"
+	"loading_time/internal/app/session"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v4"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/crypto/bcrypt"
 )
 
 // =========================================================
-// 🔐 JWT + роли
+// 👤 USERS (регистрация / вход / профиль)
 // =========================================================
 
-var jwtKey = []byte("super_secret_key")
-
-type Claims struct {
-	UserID int    `json:"user_id"`
-	Role   string `json:"role"`
-	jwt.RegisteredClaims
+type UserHandler struct {
+	Repository *repository.Repository
+	Tokens     *auth.TokenService
+	Sessions   session.Store
+	RefreshTTL time.Duration // срок жизни refresh-токена
 }
 
-// Middleware проверки токена и роли
-func (h *UserHandler) AuthMiddleware(allowedRoles ...string) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		tokenStr := c.GetHeader("Authorization")
-		if tokenStr == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Требуется авторизация"})
-			c.Abort()
-			return
-		}
-
-		tokenStr = strings.TrimPrefix(tokenStr, "Bearer ")
-		claims := &Claims{}
-		token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
-			return jwtKey, nil
-		})
-		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Неверный токен"})
-			c.Abort()
-			return
-		}
-
-		// Проверка роли
-		allowed := false
-		for _, role := range allowedRoles {
-			if claims.Role == role {
-				allowed = true
-				break
-			}
-		}
-		if !allowed {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
-			c.Abort()
-			return
-		}
+// defaultRefreshTTL - срок жизни refresh-токена, если JWT.RefreshTTL не задан в конфигурации
+const defaultRefreshTTL = 30 * 24 * time.Hour
 
-		c.Set("user_id", claims.UserID)
-		c.Set("role", claims.Role)
-		c.Next()
+func (h *UserHandler) refreshTTL() time.Duration {
+	if h.RefreshTTL > 0 {
+		return h.RefreshTTL
 	}
+	return defaultRefreshTTL
 }
 
-// =========================================================
-// 👤 USERS (регистрация / вход / профиль)
-// =========================================================
+// setSession - сессия только что выданного JWT на время его жизни; family - семейство
+// refresh-токенов входа: при его отзыве сессия отзывается тоже
+func (h *UserHandler) setSession(c *gin.Context, claims *auth.Claims, family string) error {
+	return h.Sessions.SetSession(c.Request.Context(), session.Session{
+		ID:     claims.ID,
+		UserID: claims.UserID,
+		Role:   claims.Role,
+		Family: family,
+	}, h.Tokens.TTL())
+}
 
-type UserHandler struct {
-	Repository *repository.Repository
+// tokenPairJSON - токен доступа и refresh-токен, которым его обновляют
+func tokenPairJSON(accessToken string, claims *auth.Claims, refreshToken string, refreshExpiresAt time.Time) gin.H {
+	return gin.H{
+		"token":              accessToken,
+		"expires_at":         claims.ExpiresAt.Time,
+		"refresh_token":      refreshToken,
+		"refresh_expires_at": refreshExpiresAt,
+		"role":               claims.Role,
+	}
 }
 
 // @Summary      Регистрация пользователя
-// @Description  Создаёт нового пользователя
+// @Description  Создаёт нового пользователя с ролью creator; роль из тела запроса игнорируется. Логины с префиксом guest: зарезервированы (400)
 // @Tags         users
 // @Accept       json
 // @Produce      json
@@ -93,12 +76,17 @@ func (h *UserHandler) RegisterUserAPI(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	if user.Role == "" {
-		user.Role = "creator"
-	}
+	// роль назначается сервером: иначе любой зарегистрировался бы модератором
+	user.UserID = 0
+	user.Role = policy.RoleCreator
 
 	// Не хешируем здесь пароль — это делает repository.CreateUser
-	if err := h.Repository.CreateUser(&user); err != nil {
+	err := h.Repository.CreateUser(&user)
+	if errors.Is(err, repository.ErrReservedLogin) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -108,7 +96,7 @@ func (h *UserHandler) RegisterUserAPI(c *gin.Context) {
 }
 
 // @Summary      Вход пользователя
-// @Description  Аутентификация и выдача JWT
+// @Description  Аутентификация: короткоживущий JWT и refresh-токен для POST /api/users/refresh
 // @Tags         users
 // @Accept       json
 // @Produce      json
@@ -133,14 +121,6 @@ func (h *UserHandler) LoginUserAPI(c *gin.Context) {
 		return
 	}
 
-	// Лог перед bcrypt
-	logrus.Infof("LoginUserAPI DEBUG: RAW='%s' LEN=%d | HASH='%s' LEN=%d",
-		cred.Password, len(cred.Password),
-		user.Password, len(user.Password))
-	logrus.Infof("LoginUserAPI: найден пользователь %s с хешем %s, введённый пароль: %s", user.Login, user.Password, cred.Password)
-	// debug: показать что реально сравниваем
-	fmt.Printf("RAW: '%s' | HASH: '%s'\n", cred.Password, user.Password)
-
 	// Проверка пароля
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(cred.Password)); err != nil {
 		logrus.Infof("LoginUserAPI: пароль не совпадает для пользователя %s", cred.Login)
@@ -149,38 +129,157 @@ func (h *UserHandler) LoginUserAPI(c *gin.Context) {
 	}
 
 	// Генерация JWT
-	tokenString, err := utils.GenerateJWT(user.UserID, user.Role)
+	tokenString, claims, err := h.Tokens.Issue(user.UserID, user.Role)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при создании токена"})
 		return
 	}
 
-	// Сохраняем сессию в Redis на 2 часа
-	_ = utils.SetSession(tokenString, user.UserID, user.Role, 2*time.Hour)
+	refresh, err := h.Sessions.CreateRefreshToken(c.Request.Context(), user.UserID, h.refreshTTL())
+	if err != nil {
+		logrus.Errorf("LoginUserAPI: не удалось выдать refresh-токен для %s: %v", user.Login, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при создании токена"})
+		return
+	}
+
+	// Сохраняем сессию на время жизни токена: без неё AuthMiddleware токен не примет
+	if err := h.setSession(c, claims, refresh.Family); err != nil {
+		logrus.Errorf("LoginUserAPI: не удалось сохранить сессию для %s: %v", user.Login, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при создании сессии"})
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Успешный вход",
-		"token":   tokenString,
-		"role":    user.Role,
-	})
+	// Переносим гостевой черновик в черновик пользователя
+	if guestToken, err := c.Cookie(GuestCookieName); err == nil && guestToken != "" {
+		if err := h.Repository.MergeGuestDraft(guestToken, user.UserID); err != nil {
+			logrus.Errorf("LoginUserAPI: не удалось перенести гостевой черновик для %s: %v", user.Login, err)
+		}
+		c.SetCookie(GuestCookieName, "", -1, "/", "", false, true)
+	}
+
+	response := tokenPairJSON(tokenString, claims, refresh.Token, refresh.ExpiresAt)
+	response["message"] = "Успешный вход"
+	c.JSON(http.StatusOK, response)
+}
+
+// @Summary      Обновление токена
+// @Description  Обменивает refresh-токен на новую пару токенов; старый refresh-токен перестаёт действовать. Повторное предъявление уже обменянного токена отзывает все токены этого входа
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        body  body      object{refresh_token=string}  true  "Refresh-токен"
+// @Success      200  {object}  object "token: string, expires_at: string, refresh_token: string, refresh_expires_at: string, role: string"
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /api/users/refresh [post]
+func (h *UserHandler) RefreshTokenAPI(c *gin.Context) {
+	var input struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || input.RefreshToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "refresh_token is required"})
+		return
+	}
+
+	refresh, err := h.Sessions.RotateRefreshToken(c.Request.Context(), input.RefreshToken, h.refreshTTL())
+	if errors.Is(err, session.ErrRefreshTokenReused) {
+		logrus.Warnf("RefreshTokenAPI: повторное использование refresh-токена пользователя, семейство отозвано")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, session.ErrRefreshTokenInvalid) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// роль берём из базы: она могла измениться с момента входа
+	user, err := h.Repository.GetUserByID(refresh.UserID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": session.ErrRefreshTokenInvalid.Error()})
+		return
+	}
+
+	tokenString, claims, err := h.Tokens.Issue(user.UserID, user.Role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при создании токена"})
+		return
+	}
+	err = h.setSession(c, claims, refresh.Family)
+	if errors.Is(err, session.ErrRefreshTokenInvalid) {
+		// семейство отозвано между обменом и созданием сессии
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при создании сессии"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokenPairJSON(tokenString, claims, refresh.Token, refresh.ExpiresAt))
 }
 
 // @Summary      Выход пользователя
-// @Description  Удаление JWT и сессии
+// @Description  Отзывает сессию предъявленного JWT; если передан refresh_token, отзываются и refresh-токены этого входа
 // @Tags         users
+// @Accept       json
 // @Produce      json
+// @Param        body  body      object{refresh_token=string}  false  "Refresh-токен этого входа"
 // @Success      200  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Security     ApiKeyAuth
 // @Router       /api/users/logout [post]
 func (h *UserHandler) LogoutUserAPI(c *gin.Context) {
-	token, _ := c.Cookie("jwt")
-	if token != "" {
-		_ = utils.DeleteSession(token)
+	if err := h.Sessions.DeleteSession(c.Request.Context(), c.GetString(middleware.SessionIDKey)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var input struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	// тело необязательно
+	if err := c.ShouldBindJSON(&input); err == nil && input.RefreshToken != "" {
+		if err := h.Sessions.RevokeRefreshToken(c.Request.Context(), input.RefreshToken); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
 	}
 
-	c.SetCookie("jwt", "", -1, "/", "", false, true)
 	c.JSON(http.StatusOK, gin.H{"message": "Logout successful"})
 }
 
+// @Summary      Выход на всех устройствах
+// @Description  Отзывает все сессии и refresh-токены текущего пользователя
+// @Tags         users
+// @Produce      json
+// @Success      200  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Router       /api/users/logout-all [post]
+func (h *UserHandler) LogoutAllUserAPI(c *gin.Context) {
+	userID := c.GetInt("user_id")
+
+	// сначала refresh-токены, чтобы по ним нельзя было получить новую сессию
+	if err := h.Sessions.RevokeUserRefreshTokens(c.Request.Context(), userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.Sessions.DeleteUserSessions(c.Request.Context(), userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logrus.Infof("LogoutAllUserAPI: все сессии пользователя %d отозваны", userID)
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out on all devices"})
+}
+
 // @Summary      Профиль пользователя
 // @Description  Получение данных профиля авторизованного пользователя
 // @Tags         users
@@ -225,6 +324,7 @@ func (h *UserHandler) UpdateUserProfileAPI(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	user.Role = "" // роль через профиль не меняется (Updates пропускает пустые поля)
 	switch v := uid.(type) {
 	case float64:
 		user.UserID = int(v)
@@ -234,7 +334,12 @@ func (h *UserHandler) UpdateUserProfileAPI(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user id type"})
 		return
 	}
-	if err := h.Repository.UpdateUser(user); err != nil {
+	err := h.Repository.UpdateUser(user)
+	if errors.Is(err, repository.ErrReservedLogin) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
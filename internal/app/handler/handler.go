@@ -1,80 +1,138 @@
 package handler
 
 import (
+	"html/template"
+
+	"loading_time/internal/app/auth"
+	"loading_time/internal/app/config"
 	"loading_time/internal/app/handler/api"
 	"loading_time/internal/app/handler/middleware"
+	"loading_time/internal/app/images"
 	"loading_time/internal/app/repository"
+	"loading_time/internal/app/session"
+	"loading_time/internal/app/storage"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
 
 type Handler struct {
-	Repository            *repository.Repository
-	ShipAPIHandler        *api.ShipHandler
-	RequestShipAPIHandler *api.RequestShipHandler
-	UserAPIHandler        *api.UserHandler
+	Repository               *repository.Repository
+	ShipAPIHandler           *api.ShipHandler
+	RequestShipAPIHandler    *api.RequestShipHandler
+	UserAPIHandler           *api.UserHandler
+	RecommendationAPIHandler *api.RecommendationHandler
+	TerminalAPIHandler       *api.TerminalHandler
+	ImageStore               storage.ImageStore
+	Tokens                   *auth.TokenService
+	Sessions                 session.Store
 }
 
-func NewHandler(rep *repository.Repository) *Handler {
+func NewHandler(rep *repository.Repository, imageStore storage.ImageStore, tokens *auth.TokenService, sessions session.Store, conf *config.Config) *Handler {
 	return &Handler{
-		Repository:            rep,
-		ShipAPIHandler:        &api.ShipHandler{Repository: rep},
-		RequestShipAPIHandler: &api.RequestShipHandler{Repository: rep},
-		UserAPIHandler:        &api.UserHandler{Repository: rep},
+		Repository:               rep,
+		ImageStore:               imageStore,
+		Tokens:                   tokens,
+		Sessions:                 sessions,
+		ShipAPIHandler:           &api.ShipHandler{Repository: rep, ImageStore: imageStore, PresignExpiry: conf.ImageStore.PresignExpiry},
+		RequestShipAPIHandler:    &api.RequestShipHandler{Repository: rep, DeletedRetention: conf.RequestShip.DeletedRetention},
+		UserAPIHandler:           &api.UserHandler{Repository: rep, Tokens: tokens, Sessions: sessions, RefreshTTL: conf.JWT.RefreshTTL},
+		RecommendationAPIHandler: &api.RecommendationHandler{Repository: rep},
+		TerminalAPIHandler:       &api.TerminalHandler{Repository: rep},
 	}
 }
 
 func (h *Handler) SetupRoutes(router *gin.Engine) {
 	router.GET("/ship/:id", h.GetShip)
-	router.GET("/request_ship", h.CreateOrRedirectRequestShip)
-	router.GET("/request_ship/:id", h.GetRequestShip)
-	router.POST("/request_ship/calculate_loading_time/:id", h.CalculateLoadingTime)
-	router.GET("/ships", h.GetShips)
+	router.GET("/request_ship", middleware.OptionalAuthMiddleware(h.Tokens, h.Sessions), h.CreateOrRedirectRequestShip)
+	// страница заявки - только владельцу (в том числе гостю по cookie) и модератору
+	ownRequestPage := middleware.RequestShipAccessMiddleware(h.Repository, h.draftSubject, "id")
+	router.GET("/request_ship/:id", middleware.OptionalAuthMiddleware(h.Tokens, h.Sessions), ownRequestPage, h.GetRequestShip)
+	router.POST("/request_ship/calculate_loading_time/:id", middleware.OptionalAuthMiddleware(h.Tokens, h.Sessions), ownRequestPage, h.CalculateLoadingTime)
+	router.GET("/ships", middleware.OptionalAuthMiddleware(h.Tokens, h.Sessions), h.GetShips)
 
 	// API маршруты
 	apiGroup := router.Group("/api")
 	{
 		//  1. ГОСТЬ: Чтение + регистрация/вход
-		apiGroup.GET("/ships", h.ShipAPIHandler.GetShipsAPI)
+		apiGroup.GET("/ships", middleware.OptionalAuthMiddleware(h.Tokens, h.Sessions), h.ShipAPIHandler.GetShipsAPI)
 		apiGroup.GET("/ships/:id", h.ShipAPIHandler.GetShipAPI)
-		apiGroup.GET("/request_ship/basket", h.RequestShipAPIHandler.GetRequestShipBasketAPI)
+		apiGroup.GET("/ships/:id/photos", h.ShipAPIHandler.GetShipPhotosAPI)
+		apiGroup.GET("/ships/:id/image/download-url", h.ShipAPIHandler.GetShipImageDownloadURLAPI)
+		apiGroup.GET("/terminals", h.TerminalAPIHandler.GetTerminalsAPI)
+		apiGroup.GET("/terminals/:id", h.TerminalAPIHandler.GetTerminalAPI)
+		apiGroup.GET("/berths/:id", h.TerminalAPIHandler.GetBerthAPI)
+
+		// Черновик: авторизованный пользователь или гость по cookie
+		draftGroup := apiGroup.Group("", middleware.OptionalAuthMiddleware(h.Tokens, h.Sessions))
+		{
+			draftGroup.GET("/request_ship/basket", h.RequestShipAPIHandler.GetRequestShipBasketAPI)
+			draftGroup.POST("/ships/:id/add-to-ship-bucket", h.ShipAPIHandler.AddShipToRequestShipAPI)
+			draftGroup.POST("/recommendations", h.RecommendationAPIHandler.RecommendFleetAPI)
+		}
 
 		// Регистрация и вход — ГОСТЬ
 		apiGroup.POST("/users/register", h.UserAPIHandler.RegisterUserAPI)
 		apiGroup.POST("/users/login", h.UserAPIHandler.LoginUserAPI)
+		apiGroup.POST("/users/refresh", h.UserAPIHandler.RefreshTokenAPI)
 
 		//  2. АВТОРИЗОВАННЫЕ (creator + moderator)
-		authGroup := apiGroup.Group("", middleware.AuthMiddleware())
+		authGroup := apiGroup.Group("", middleware.AuthMiddleware(h.Tokens, h.Sessions))
 		{
-			// УСЛУГИ
-			authGroup.POST("/ships", h.ShipAPIHandler.CreateShipAPI)
-			authGroup.PUT("/ships/:id", h.ShipAPIHandler.UpdateShipAPI)
-			authGroup.DELETE("/ships/:id", h.ShipAPIHandler.DeleteShipAPI)
-			authGroup.POST("/ships/:id/add-to-ship-bucket", h.ShipAPIHandler.AddShipToRequestShipAPI)
-			authGroup.POST("/ships/:id/image", h.ShipAPIHandler.AddShipImageAPI)
-
-			// ЗАЯВКИ
+			// ЗАЯВКИ: список только своих (кроме модератора), отдельная заявка - владельцу или модератору
 			authGroup.GET("/request_ship", h.RequestShipAPIHandler.GetRequestShipsAPI)
-			authGroup.GET("/request_ship/:id", h.RequestShipAPIHandler.GetRequestShipAPI)
-			authGroup.PUT("/request_ship/:id", h.RequestShipAPIHandler.UpdateRequestShipAPI)
-			authGroup.PUT("/request_ship/:id/formation", h.RequestShipAPIHandler.FormRequestShipAPI)
-			authGroup.DELETE("/request_ship/:id", h.RequestShipAPIHandler.DeleteRequestShipAPI)
+			ownRequestGroup := authGroup.Group("/request_ship/:id", middleware.RequestShipAccessMiddleware(h.Repository, middleware.TokenSubject, "id"))
+			{
+				ownRequestGroup.GET("", h.RequestShipAPIHandler.GetRequestShipAPI)
+				ownRequestGroup.PUT("", h.RequestShipAPIHandler.UpdateRequestShipAPI)
+				ownRequestGroup.PUT("/formation", h.RequestShipAPIHandler.FormRequestShipAPI)
+				ownRequestGroup.POST("/simulate", h.RequestShipAPIHandler.SimulateRequestShipAPI)
+				ownRequestGroup.DELETE("", h.RequestShipAPIHandler.DeleteRequestShipAPI)
 
-			// М-М
-			authGroup.PUT("/request_ship/:id/ships/:ship_id", h.RequestShipAPIHandler.UpdateShipInRequestAPI)
-			authGroup.DELETE("/request_ship/:id/ships/:ship_id", h.RequestShipAPIHandler.DeleteShipFromRequestShipAPI)
+				// М-М
+				ownRequestGroup.PUT("/ships/:ship_id", h.RequestShipAPIHandler.UpdateShipInRequestAPI)
+				ownRequestGroup.DELETE("/ships/:ship_id", h.RequestShipAPIHandler.DeleteShipFromRequestShipAPI)
+			}
 
 			// ПРОФИЛЬ
 			authGroup.POST("/users/logout", h.UserAPIHandler.LogoutUserAPI)
+			authGroup.POST("/users/logout-all", h.UserAPIHandler.LogoutAllUserAPI)
 			authGroup.GET("/users/profile", h.UserAPIHandler.GetUserProfileAPI)
 			authGroup.PUT("/users/profile", h.UserAPIHandler.UpdateUserProfileAPI)
 		}
 
 		//  3. ТОЛЬКО МОДЕРАТОР
-		modGroup := apiGroup.Group("", middleware.ModeratorMiddleware())
+		modGroup := apiGroup.Group("", middleware.AuthMiddleware(h.Tokens, h.Sessions), middleware.ModeratorMiddleware())
 		{
 			modGroup.PUT("/request_ship/:id/completion", h.RequestShipAPIHandler.CompleteRequestShipAPI)
+			modGroup.GET("/request_ship/:id/history", h.RequestShipAPIHandler.GetRequestShipHistoryAPI)
+			modGroup.DELETE("/request_ship/:id/purge", h.RequestShipAPIHandler.PurgeRequestShipAPI)
+		}
+
+		//  4. КАТАЛОГ: корабли, их изображения, терминалы и причалы меняет только модератор (policy.CanMutateCatalog)
+		catalogGroup := apiGroup.Group("", middleware.AuthMiddleware(h.Tokens, h.Sessions), middleware.CatalogEditorMiddleware())
+		{
+			// УСЛУГИ
+			catalogGroup.POST("/ships", h.ShipAPIHandler.CreateShipAPI)
+			catalogGroup.PUT("/ships/:id", h.ShipAPIHandler.UpdateShipAPI)
+			catalogGroup.DELETE("/ships/:id", h.ShipAPIHandler.DeleteShipAPI)
+			catalogGroup.PUT("/ships/:id/restore", h.ShipAPIHandler.RestoreShipAPI)
+			catalogGroup.POST("/ships/:id/image", h.ShipAPIHandler.AddShipImageAPI)
+			catalogGroup.POST("/ships/:id/image/upload-url", h.ShipAPIHandler.CreateShipImageUploadURLAPI)
+			catalogGroup.POST("/ships/:id/image/confirm", h.ShipAPIHandler.ConfirmShipImageUploadAPI)
+			catalogGroup.POST("/ships/:id/photos", h.ShipAPIHandler.AddShipPhotoAPI)
+			catalogGroup.PUT("/ships/:id/photos", h.ShipAPIHandler.ReorderShipPhotosAPI)
+			catalogGroup.PUT("/ships/:id/photos/:photo_id", h.ShipAPIHandler.UpdateShipPhotoAPI)
+			catalogGroup.POST("/ships/:id/photos/:photo_id/primary", h.ShipAPIHandler.SetPrimaryShipPhotoAPI)
+			catalogGroup.DELETE("/ships/:id/photos/:photo_id", h.ShipAPIHandler.DeleteShipPhotoAPI)
+
+			// ТЕРМИНАЛЫ И ПРИЧАЛЫ
+			catalogGroup.POST("/terminals", h.TerminalAPIHandler.CreateTerminalAPI)
+			catalogGroup.PUT("/terminals/:id", h.TerminalAPIHandler.UpdateTerminalAPI)
+			catalogGroup.DELETE("/terminals/:id", h.TerminalAPIHandler.DeleteTerminalAPI)
+			catalogGroup.POST("/terminals/:id/berths", h.TerminalAPIHandler.CreateBerthAPI)
+			catalogGroup.PUT("/berths/:id", h.TerminalAPIHandler.UpdateBerthAPI)
+			catalogGroup.DELETE("/berths/:id", h.TerminalAPIHandler.DeleteBerthAPI)
 		}
 	}
 }
@@ -82,6 +140,32 @@ func (h *Handler) SetupRoutes(router *gin.Engine) {
 func (h *Handler) RegisterStatic(router *gin.Engine) {
 	router.Static("/styles", "./resources/styles")
 	router.Static("/img", "./resources/img")
+
+	// при локальном хранилище изображения кораблей раздаёт сам сервер
+	if local, ok := h.ImageStore.(*storage.LocalStore); ok {
+		files := gin.WrapH(local.Handler())
+		router.GET(local.URLPrefix+"/*filepath", files)
+		router.HEAD(local.URLPrefix+"/*filepath", files)
+	}
+}
+
+// TemplateFuncs - функции для HTML-шаблонов (регистрируются до LoadHTMLGlob)
+func (h *Handler) TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		// shipImageURL - ссылка на изображение корабля по Ship.PhotoURL;
+		// необязательный второй аргумент - размер ("thumb", "medium"), по умолчанию оригинал
+		"shipImageURL": func(photoURL string, rendition ...string) string {
+			if len(rendition) > 0 {
+				photoURL = images.RenditionName(photoURL, rendition[0])
+			}
+			return h.ImageStore.URL(images.ObjectName(photoURL))
+		},
+		// highlight - фрагмент из ds.ShipMatch: текст уже экранирован в репозитории,
+		// совпадения размечены <mark>
+		"highlight": func(snippet string) template.HTML {
+			return template.HTML(snippet)
+		},
+	}
 }
 
 func (h *Handler) errorHandler(c *gin.Context, code int, err error) {
@@ -4,13 +4,15 @@ import (
 	"net/http"
 	"strings"
 
-	"loading_time/internal/app/utils"
+	"loading_time/internal/app/auth"
+	"loading_time/internal/app/session"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 )
 
-// AuthMiddleware проверяет JWT и допустимые роли
-func AuthMiddleware(allowedRoles ...string) gin.HandlerFunc {
+// AuthMiddleware проверяет JWT (auth.TokenService), что его сессия в sessions не отозвана, и допустимые роли
+func AuthMiddleware(tokens *auth.TokenService, sessions session.Store, allowedRoles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -18,17 +20,11 @@ func AuthMiddleware(allowedRoles ...string) gin.HandlerFunc {
 			return
 		}
 
-		tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
-		claims, err := utils.ParseJWT(tokenStr)
-		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+		claims, ok := authenticate(c, tokens, sessions, authHeader)
+		if !ok {
 			return
 		}
 
-		// сохраняем данные в контекст Gin
-		c.Set("user_id", claims.UserID)
-		c.Set("role", claims.Role)
-
 		// Проверяем роль, если переданы allowedRoles
 		if len(allowedRoles) > 0 {
 			allowed := false
@@ -48,16 +44,62 @@ func AuthMiddleware(allowedRoles ...string) gin.HandlerFunc {
 	}
 }
 
-// ModeratorMiddleware — требует роль "moderator"
-func ModeratorMiddleware() gin.HandlerFunc {
+// OptionalAuthMiddleware — как AuthMiddleware, но запрос без токена пропускается дальше как гостевой
+func OptionalAuthMiddleware(tokens *auth.TokenService, sessions session.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		roleAny, exists := c.Get("role")
-		if !exists {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Moderator access required"})
+		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" {
+			c.Next()
 			return
 		}
-		role, ok := roleAny.(string)
-		if !ok || role != "moderator" {
+
+		if _, ok := authenticate(c, tokens, sessions, authHeader); !ok {
+			return
+		}
+		c.Next()
+	}
+}
+
+// authenticate - разбирает токен из заголовка и проверяет его сессию;
+// при успехе кладёт user_id, role и session_id в контекст, иначе прерывает запрос
+func authenticate(c *gin.Context, tokens *auth.TokenService, sessions session.Store, authHeader string) (*auth.Claims, bool) {
+	claims, err := tokens.Parse(bearerToken(authHeader))
+	if err != nil || claims.ID == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+		return nil, false
+	}
+
+	active, err := sessions.SessionActive(c.Request.Context(), claims.ID)
+	if err != nil {
+		logrus.Errorf("authenticate: не удалось проверить сессию: %v", err)
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Session store unavailable"})
+		return nil, false
+	}
+	if !active {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Session revoked or expired"})
+		return nil, false
+	}
+
+	// сохраняем данные в контекст Gin
+	c.Set("user_id", claims.UserID)
+	c.Set("role", claims.Role)
+	c.Set(SessionIDKey, claims.ID)
+	return claims, true
+}
+
+// SessionIDKey - ключ контекста с id сессии (jti) текущего токена
+const SessionIDKey = "session_id"
+
+// bearerToken - токен из заголовка Authorization ("Bearer <token>")
+func bearerToken(authHeader string) string {
+	return strings.TrimPrefix(authHeader, "Bearer ")
+}
+
+// ModeratorMiddleware — требует роль "moderator"
+func ModeratorMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		subject, _ := SubjectFromContext(c)
+		if !subject.IsModerator() {
 			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Moderator access required"})
 			return
 		}
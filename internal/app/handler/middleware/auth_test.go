@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"loading_time/internal/app/auth"
+	"loading_time/internal/app/config"
+	"loading_time/internal/app/session"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAuthMiddlewareSessions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	tokens, err := auth.NewTokenService(config.JWTConfig{
+		Issuer:       "loading_time",
+		Audience:     "loading_time_api",
+		TTL:          time.Hour,
+		SigningKeyID: "main",
+		Keys:         map[string]string{"main": "main-secret"},
+	})
+	if err != nil {
+		t.Fatalf("NewTokenService: %v", err)
+	}
+	sessions := session.NewMemoryStore()
+
+	router := gin.New()
+	router.GET("/me", AuthMiddleware(tokens, sessions), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	get := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	token, claims, err := tokens.Issue(7, "creator")
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if code := get(token); code != http.StatusUnauthorized {
+		t.Errorf("token without session: status %d, want %d", code, http.StatusUnauthorized)
+	}
+
+	err = sessions.SetSession(ctx, session.Session{ID: claims.ID, UserID: claims.UserID, Role: claims.Role}, tokens.TTL())
+	if err != nil {
+		t.Fatalf("SetSession: %v", err)
+	}
+	if code := get(token); code != http.StatusOK {
+		t.Errorf("token with session: status %d, want %d", code, http.StatusOK)
+	}
+
+	if err := sessions.DeleteUserSessions(ctx, claims.UserID); err != nil {
+		t.Fatalf("DeleteUserSessions: %v", err)
+	}
+	if code := get(token); code != http.StatusUnauthorized {
+		t.Errorf("token after logout-all: status %d, want %d", code, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"loading_time/internal/app/policy"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SubjectFromContext - субъект из AuthMiddleware/OptionalAuthMiddleware; ok = false для запроса без токена
+func SubjectFromContext(c *gin.Context) (subject policy.Subject, ok bool) {
+	if _, exists := c.Get("user_id"); !exists {
+		return policy.Subject{}, false
+	}
+	return policy.Subject{UserID: c.GetInt("user_id"), Role: c.GetString("role")}, true
+}
+
+// SubjectResolver - как определить субъект запроса (по токену или, для HTML-страниц, по cookie гостя)
+type SubjectResolver func(c *gin.Context) (policy.Subject, bool, error)
+
+// TokenSubject - SubjectResolver для маршрутов за AuthMiddleware
+func TokenSubject(c *gin.Context) (policy.Subject, bool, error) {
+	subject, ok := SubjectFromContext(c)
+	return subject, ok, nil
+}
+
+// RequestShipOwners - владелец заявки по ID (удалённые заявки тоже находятся)
+type RequestShipOwners interface {
+	GetRequestShipOwnerID(requestShipID int) (int, error)
+}
+
+// RequestShipAccessMiddleware - доступ к заявке из параметра пути param
+// только владельцу и модератору (policy.CanAccessRequestShip)
+func RequestShipAccessMiddleware(owners RequestShipOwners, resolve SubjectResolver, param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestShipID, err := strconv.Atoi(c.Param(param))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid request ID"})
+			return
+		}
+
+		subject, ok, err := resolve(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": policy.ErrForbidden.Error()})
+			return
+		}
+
+		ownerID, err := owners.GetRequestShipOwnerID(requestShipID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Request not found"})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := policy.CanAccessRequestShip(subject, ownerID); err != nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.Next()
+	}
+}
+
+// CatalogEditorMiddleware - изменения каталога по policy.CanMutateCatalog; ставится после AuthMiddleware
+func CatalogEditorMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		subject, _ := SubjectFromContext(c)
+		if err := policy.CanMutateCatalog(subject); err != nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.Next()
+	}
+}
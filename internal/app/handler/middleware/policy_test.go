@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"loading_time/internal/app/policy"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// fakeOwners - владельцы заявок по ID; отсутствующая заявка - gorm.ErrRecordNotFound
+type fakeOwners map[int]int
+
+func (f fakeOwners) GetRequestShipOwnerID(requestShipID int) (int, error) {
+	if requestShipID == 500 {
+		return 0, errors.New("database is down")
+	}
+	ownerID, ok := f[requestShipID]
+	if !ok {
+		return 0, gorm.ErrRecordNotFound
+	}
+	return ownerID, nil
+}
+
+func TestRequestShipAccessMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	owners := fakeOwners{1: 7}
+
+	tests := []struct {
+		name    string
+		subject *policy.Subject // nil - запрос без токена
+		path    string
+		want    int
+	}{
+		{"guest", nil, "/request_ship/1", http.StatusForbidden},
+		{"creator owner", &policy.Subject{UserID: 7, Role: policy.RoleCreator}, "/request_ship/1", http.StatusOK},
+		{"creator non-owner", &policy.Subject{UserID: 8, Role: policy.RoleCreator}, "/request_ship/1", http.StatusForbidden},
+		{"moderator", &policy.Subject{UserID: 9, Role: policy.RoleModerator}, "/request_ship/1", http.StatusOK},
+		{"missing request", &policy.Subject{UserID: 7, Role: policy.RoleCreator}, "/request_ship/2", http.StatusNotFound},
+		{"invalid id", &policy.Subject{UserID: 7, Role: policy.RoleCreator}, "/request_ship/abc", http.StatusBadRequest},
+		{"owner lookup error", &policy.Subject{UserID: 9, Role: policy.RoleModerator}, "/request_ship/500", http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			// вместо AuthMiddleware - субъект сразу в контексте
+			setSubject := func(c *gin.Context) {
+				if tt.subject != nil {
+					c.Set("user_id", tt.subject.UserID)
+					c.Set("role", tt.subject.Role)
+				}
+			}
+			router.GET("/request_ship/:id", setSubject, RequestShipAccessMiddleware(owners, TokenSubject, "id"), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.want {
+				t.Errorf("GET %s as %+v: status %d, want %d", tt.path, tt.subject, w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -3,6 +3,9 @@ package handler
 import (
 	"fmt"
 	"loading_time/internal/app/ds"
+	"loading_time/internal/app/handler/api"
+	"loading_time/internal/app/handler/middleware"
+	"loading_time/internal/app/policy"
 	"net/http"
 	"strconv"
 
@@ -24,7 +27,7 @@ func (h *Handler) GetRequestShip(ctx *gin.Context) {
 		return
 	}
 
-	requestShip, err := h.Repository.GetRequestShipExcludingDeleted(requestShipID)
+	requestShip, err := h.Repository.GetRequestShip(requestShipID)
 	if err != nil {
 		logrus.Errorf("Заявка не найдена или удалена: %v", err)
 		ctx.HTML(http.StatusNotFound, "PageNotFound.html", gin.H{
@@ -33,14 +36,43 @@ func (h *Handler) GetRequestShip(ctx *gin.Context) {
 		return
 	}
 
+	terminals, err := h.Repository.GetTerminals()
+	if err != nil {
+		logrus.Errorf("Ошибка получения терминалов: %v", err)
+	}
+
 	ctx.HTML(http.StatusOK, "request_ship.html", gin.H{
 		"request_ship": requestShip,
+		"terminals":    terminals,
 	})
 }
 
+// draftSubject - субъект HTML-страниц заявки: пользователь из токена,
+// иначе гость по cookie черновика; ok = false, если нет ни того, ни другого
+func (h *Handler) draftSubject(c *gin.Context) (policy.Subject, bool, error) {
+	if subject, ok := middleware.SubjectFromContext(c); ok {
+		return subject, true, nil
+	}
+	userID, ok, err := api.ExistingDraftOwnerID(c, h.Repository)
+	if err != nil || !ok {
+		return policy.Subject{}, false, err
+	}
+	return policy.Subject{UserID: userID, Role: policy.RoleGuest}, true, nil
+}
+
 // GET /request_ship - редирект на черновик
 func (h *Handler) CreateOrRedirectRequestShip(ctx *gin.Context) {
-	requestShip, err := h.Repository.GetOrCreateUserDraft(1)
+	userID, err := api.DraftOwnerID(ctx, h.Repository)
+	if err != nil {
+		logrus.Error(err)
+		ctx.HTML(http.StatusInternalServerError, "request_ship.html", gin.H{
+			"request_ship": ds.RequestShip{},
+			"error":        "Не удалось определить пользователя",
+		})
+		return
+	}
+
+	requestShip, err := h.Repository.GetOrCreateUserDraft(userID)
 	if err != nil {
 		logrus.Error(err)
 		ctx.HTML(http.StatusInternalServerError, "request_ship.html", gin.H{
@@ -61,18 +93,24 @@ func (h *Handler) AddShipToRequestShip(c *gin.Context) {
 		return
 	}
 
-	requestShip, err := h.Repository.GetOrCreateUserDraft(1)
+	userID, err := api.DraftOwnerID(c, h.Repository)
 	if err != nil {
 		h.errorHandler(c, http.StatusInternalServerError, err)
 		return
 	}
 
-	err = h.Repository.AddShipToRequestShip(requestShip.RequestShipID, shipID)
+	requestShip, err := h.Repository.GetOrCreateUserDraft(userID)
 	if err != nil {
 		h.errorHandler(c, http.StatusInternalServerError, err)
 		return
 	}
 
+	err = h.Repository.AddShipToRequestShip(requestShip.RequestShipID, shipID, userID)
+	if err != nil {
+		h.errorHandler(c, api.MutationStatus(err), err)
+		return
+	}
+
 	logrus.Infof("Корабль %d добавлен в заявку %d через ORM", shipID, requestShip.RequestShipID)
 	c.Redirect(http.StatusFound, fmt.Sprintf("/request_ship/%d", requestShip.RequestShipID))
 }
@@ -94,11 +132,17 @@ func (h *Handler) RemoveShipFromRequestShip(c *gin.Context) {
 		return
 	}
 
+	actorID, err := api.DraftOwnerID(c, h.Repository)
+	if err != nil {
+		h.errorHandler(c, http.StatusInternalServerError, err)
+		return
+	}
+
 	logrus.Infof("Удаление корабля %d из заявки %d", shipID, requestShipID)
 
-	err = h.Repository.RemoveShipFromRequestShip(requestShipID, shipID)
+	err = h.Repository.RemoveShipFromRequestShip(requestShipID, shipID, actorID)
 	if err != nil {
-		h.errorHandler(c, http.StatusInternalServerError, err)
+		h.errorHandler(c, api.MutationStatus(err), err)
 		return
 	}
 
@@ -114,11 +158,17 @@ func (h *Handler) DeleteRequestShip(c *gin.Context) {
 		return
 	}
 
-	if err := h.Repository.DeleteRequestShipSQL(requestShipID); err != nil {
+	actorID, err := api.DraftOwnerID(c, h.Repository)
+	if err != nil {
 		h.errorHandler(c, http.StatusInternalServerError, err)
 		return
 	}
 
+	if err := h.Repository.DeleteRequestShipSQL(requestShipID, actorID); err != nil {
+		h.errorHandler(c, api.MutationStatus(err), err)
+		return
+	}
+
 	logrus.Infof("Заявка %d помечена как удалённая", requestShipID)
 	c.Redirect(http.StatusFound, "/ships")
 }
@@ -135,12 +185,25 @@ func (h *Handler) CalculateLoadingTime(c *gin.Context) {
 	containers40ft, _ := strconv.Atoi(c.PostForm("containers_40ft"))
 	comment := c.PostForm("comment")
 
-	err = h.Repository.UpdateRequestShipFields(requestShipID, containers20ft, containers40ft, comment)
+	// пустое значение в списке причалов снимает причал с заявки
+	var berthID *int
+	if value, ok := c.GetPostForm("berth_id"); ok {
+		id, _ := strconv.Atoi(value)
+		berthID = &id
+	}
+
+	actorID, err := api.DraftOwnerID(c, h.Repository)
 	if err != nil {
 		h.errorHandler(c, http.StatusInternalServerError, err)
 		return
 	}
 
+	err = h.Repository.UpdateRequestShipFields(requestShipID, containers20ft, containers40ft, comment, berthID, actorID)
+	if err != nil {
+		h.errorHandler(c, api.MutationStatus(err), err)
+		return
+	}
+
 	logrus.Infof("Заявка %d обновлена: 20ft=%d, 40ft=%d, comment=%s",
 		requestShipID, containers20ft, containers40ft, comment)
 
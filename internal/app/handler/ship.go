@@ -1,7 +1,9 @@
 package handler
 
 import (
-	"loading_time/internal/app/ds"
+	"errors"
+	"loading_time/internal/app/handler/api"
+	"loading_time/internal/app/repository"
 	"net/http"
 	"strconv"
 
@@ -10,42 +12,59 @@ import (
 )
 
 func (h *Handler) GetShips(ctx *gin.Context) {
-	var ships []ds.Ship
-	var err error
-
-	searchQuery := ctx.Query("search")
-	if searchQuery == "" {
-		ships, err = h.Repository.GetShips()
-	} else {
-		ships, err = h.Repository.GetShipsByName(searchQuery)
+	// те же фильтры, сортировка и страницы, что и у GET /api/ships
+	filter, err := api.ShipFilterFromQuery(ctx)
+	if err != nil {
+		h.errorHandler(ctx, http.StatusBadRequest, err)
+		return
+	}
+	page, err := h.Repository.ListShips(filter)
+	if errors.Is(err, repository.ErrInvalidCursor) || errors.Is(err, repository.ErrCursorWithOffset) || errors.Is(err, repository.ErrRankWithoutQuery) {
+		h.errorHandler(ctx, http.StatusBadRequest, err)
+		return
 	}
 	if err != nil {
 		h.errorHandler(ctx, http.StatusInternalServerError, err)
 		return
 	}
 
+	// ссылка на следующую страницу с теми же параметрами
+	nextPageURL := ""
+	if page.NextCursor != "" {
+		query := ctx.Request.URL.Query()
+		query.Del("offset")
+		query.Set("cursor", page.NextCursor)
+		nextPageURL = "/ships?" + query.Encode()
+	}
+
 	// Получение черновика заявки
-	const fixedUserID = 1
 	requestShipCount := 0
 	requestShipID := 0
 
-	requestShip, err := h.Repository.GetOrCreateUserDraft(fixedUserID)
-	if err == nil {
-		logrus.Infof("Найдена заявка ID=%d, количество кораблей в заявке: %d", requestShip.RequestShipID, len(requestShip.Ships))
-		for i, shipInRequest := range requestShip.Ships {
-			logrus.Infof("Корабль %d: ID=%d, количество: %d", i, shipInRequest.ShipID, shipInRequest.ShipsCount)
-			requestShipCount += shipInRequest.ShipsCount
+	userID, hasDraftOwner, err := api.ExistingDraftOwnerID(ctx, h.Repository)
+	if err != nil {
+		logrus.Errorf("Ошибка определения владельца черновика: %v", err)
+	} else if hasDraftOwner {
+		requestShip, err := h.Repository.GetOrCreateUserDraft(userID)
+		if err == nil {
+			logrus.Infof("Найдена заявка ID=%d, количество кораблей в заявке: %d", requestShip.RequestShipID, len(requestShip.Ships))
+			for i, shipInRequest := range requestShip.Ships {
+				logrus.Infof("Корабль %d: ID=%d, количество: %d", i, shipInRequest.ShipID, shipInRequest.ShipsCount)
+				requestShipCount += shipInRequest.ShipsCount
+			}
+			requestShipID = requestShip.RequestShipID
+		} else {
+			logrus.Errorf("Ошибка получения заявки: %v", err)
 		}
-		requestShipID = requestShip.RequestShipID
-	} else {
-		logrus.Errorf("Ошибка получения заявки: %v", err)
 	}
 
 	logrus.Infof("Итоговый счетчик для отображения: %d", requestShipCount)
 
 	ctx.HTML(http.StatusOK, "index.html", gin.H{
-		"ships":              ships,
-		"search":             searchQuery,
+		"ships":              page.Items,
+		"total":              page.Total,
+		"next_page_url":      nextPageURL,
+		"search":             filter.Query,
 		"request_ship_count": requestShipCount,
 		"request_ship_id":    requestShipID,
 	})
@@ -65,7 +84,14 @@ func (h *Handler) GetShip(ctx *gin.Context) {
 		return
 	}
 
+	// галерея не обязательна для страницы: при ошибке показываем только основную фотографию
+	photos, err := h.Repository.GetShipPhotos(id)
+	if err != nil {
+		logrus.Errorf("Ошибка получения галереи корабля %d: %v", id, err)
+	}
+
 	ctx.HTML(http.StatusOK, "ship.html", gin.H{
-		"ship": ship,
+		"ship":   ship,
+		"photos": photos,
 	})
 }
@@ -0,0 +1,245 @@
+package images
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // регистрирует декодер WebP для image.Decode
+)
+
+const (
+	RenditionOriginal  = ""
+	RenditionMedium    = "medium"
+	RenditionThumbnail = "thumb"
+
+	// maxPixels - защита от "бомб" с огромным разрешением при маленьком файле;
+	// 25 Мп хватает снимкам камер, буфер RGBA такого размера - 100 МБ
+	maxPixels = 25_000_000
+
+	// headerSize - сколько начальных байт ProcessReader держит в памяти для определения формата,
+	// размеров и EXIF Orientation (сегмент APP1 не больше 64 КБ, но перед SOF бывают ICC-профили)
+	headerSize = 1 << 20
+)
+
+// renditionSizes - максимальная сторона каждого размера, px
+var renditionSizes = map[string]int{
+	RenditionMedium:    1024,
+	RenditionThumbnail: 320,
+}
+
+// ErrUnsupportedFormat - загружен не JPEG, PNG или WebP (по содержимому, а не по имени файла)
+var ErrUnsupportedFormat = errors.New("unsupported image format: only JPEG, PNG and WebP are allowed")
+
+// File - закодированное изображение, готовое к загрузке в хранилище
+type File struct {
+	Data        []byte
+	ContentType string
+	Ext         string
+}
+
+// Processed - оригинал без метаданных и уменьшенные копии по имени размера
+type Processed struct {
+	Original   File
+	Renditions map[string]File
+}
+
+// Sniff - определить формат по содержимому; ext - расширение, под которым хранится оригинал
+func Sniff(data []byte) (contentType, ext string, err error) {
+	switch contentType = http.DetectContentType(data); contentType {
+	case "image/jpeg":
+		return contentType, ".jpg", nil
+	case "image/png":
+		return contentType, ".png", nil
+	case "image/webp":
+		return contentType, ".webp", nil
+	default:
+		return "", "", ErrUnsupportedFormat
+	}
+}
+
+// Process - проверка формата, удаление EXIF и прочих метаданных, генерация уменьшенных копий.
+// Копии кодируются в PNG для PNG-оригиналов (сохраняется прозрачность) и в JPEG для остальных.
+func Process(data []byte) (Processed, error) {
+	contentType, ext, err := Sniff(data)
+	if err != nil {
+		return Processed{}, err
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Processed{}, fmt.Errorf("invalid image: %w", err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxPixels {
+		return Processed{}, fmt.Errorf("invalid image dimensions %dx%d", config.Width, config.Height)
+	}
+
+	orientation := 1
+	if contentType == "image/jpeg" {
+		orientation = jpegOrientation(data)
+	}
+
+	stripped, err := StripMetadata(data, contentType)
+	if err != nil {
+		return Processed{}, err
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(stripped))
+	if err != nil {
+		return Processed{}, fmt.Errorf("invalid image: %w", err)
+	}
+	img = applyOrientation(img, orientation)
+
+	result := Processed{
+		Original:   File{Data: stripped, ContentType: contentType, Ext: ext},
+		Renditions: map[string]File{},
+	}
+
+	// ориентация хранилась в удалённом EXIF - поворачиваем сам оригинал
+	if orientation != 1 {
+		result.Original, err = encode(img, ".jpg")
+		if err != nil {
+			return Processed{}, err
+		}
+	}
+
+	renditionExt := RenditionExt(ext)
+	for name, maxSide := range renditionSizes {
+		rendition, err := encode(resize(img, maxSide), renditionExt)
+		if err != nil {
+			return Processed{}, err
+		}
+		result.Renditions[name] = rendition
+	}
+	return result, nil
+}
+
+// ProcessReader - как Process, но читает изображение потоком, не загружая файл в память целиком:
+// в памяти только заголовок и декодированное изображение (не больше maxPixels). Оригинал
+// перекодируется (в PNG для PNG, иначе в JPEG), поэтому метаданные в нём не сохраняются.
+// Для больших файлов, загруженных клиентом напрямую в хранилище.
+func ProcessReader(r io.Reader) (Processed, error) {
+	reader := bufio.NewReaderSize(r, headerSize)
+	header, err := reader.Peek(headerSize)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return Processed{}, err
+	}
+
+	contentType, ext, err := Sniff(header)
+	if err != nil {
+		return Processed{}, err
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(header))
+	if err != nil {
+		return Processed{}, fmt.Errorf("invalid image: %w", err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxPixels {
+		return Processed{}, fmt.Errorf("invalid image dimensions %dx%d", config.Width, config.Height)
+	}
+
+	orientation := 1
+	if contentType == "image/jpeg" {
+		orientation = jpegOrientation(header)
+	}
+
+	img, _, err := image.Decode(reader)
+	if err != nil {
+		return Processed{}, fmt.Errorf("invalid image: %w", err)
+	}
+	img = applyOrientation(img, orientation)
+
+	renditionExt := RenditionExt(ext)
+	original, err := encode(img, renditionExt)
+	if err != nil {
+		return Processed{}, err
+	}
+	result := Processed{Original: original, Renditions: map[string]File{}}
+	for name, maxSide := range renditionSizes {
+		rendition, err := encode(resize(img, maxSide), renditionExt)
+		if err != nil {
+			return Processed{}, err
+		}
+		result.Renditions[name] = rendition
+	}
+	return result, nil
+}
+
+// ObjectPrefix - каталог изображений кораблей в хранилище
+const ObjectPrefix = "img/"
+
+// ObjectName - имя объекта в хранилище для Ship.PhotoURL (в БД хранится только имя файла)
+func ObjectName(fileName string) string {
+	if strings.Contains(fileName, "/") {
+		parts := strings.Split(fileName, "/")
+		fileName = parts[len(parts)-1]
+	}
+	return ObjectPrefix + fileName
+}
+
+// RenditionExt - расширение уменьшенных копий для оригинала с расширением ext
+func RenditionExt(ext string) string {
+	if strings.EqualFold(ext, ".png") {
+		return ".png"
+	}
+	return ".jpg"
+}
+
+// RenditionName - имя файла копии нужного размера для имени оригинала ("abc.webp" -> "abc_thumb.jpg")
+func RenditionName(fileName, rendition string) string {
+	if rendition == RenditionOriginal || fileName == "" {
+		return fileName
+	}
+	ext := filepath.Ext(fileName)
+	return strings.TrimSuffix(fileName, ext) + "_" + rendition + RenditionExt(ext)
+}
+
+// Renditions - имена всех размеров, кроме оригинала
+func Renditions() []string {
+	return []string{RenditionMedium, RenditionThumbnail}
+}
+
+// resize - уменьшение с сохранением пропорций так, чтобы большая сторона не превышала maxSide
+func resize(img image.Image, maxSide int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= maxSide && height <= maxSide {
+		return img
+	}
+
+	if width >= height {
+		height = max(1, height*maxSide/width)
+		width = maxSide
+	} else {
+		width = max(1, width*maxSide/height)
+		height = maxSide
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Over, nil)
+	return dst
+}
+
+func encode(img image.Image, ext string) (File, error) {
+	var buf bytes.Buffer
+	if ext == ".png" {
+		if err := png.Encode(&buf, img); err != nil {
+			return File{}, err
+		}
+		return File{Data: buf.Bytes(), ContentType: "image/png", Ext: ".png"}, nil
+	}
+
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+		return File{}, err
+	}
+	return File{Data: buf.Bytes(), ContentType: "image/jpeg", Ext: ".jpg"}, nil
+}
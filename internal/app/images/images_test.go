@@ -0,0 +1,281 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// testImage - небольшое изображение с разными пикселями
+func testImage(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(y*width + x + 1), G: uint8(x * 40), B: uint8(y * 40), A: 255})
+		}
+	}
+	return img
+}
+
+// exifTIFF - TIFF-заголовок EXIF с единственным тегом Orientation
+func exifTIFF(order binary.ByteOrder, orientation int) []byte {
+	tiff := make([]byte, 8+2+12+4)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8) // смещение IFD0
+	order.PutUint16(tiff[8:], 1) // одна запись
+	order.PutUint16(tiff[10:], 0x0112)
+	order.PutUint16(tiff[12:], 3) // SHORT
+	order.PutUint32(tiff[14:], 1)
+	order.PutUint16(tiff[18:], uint16(orientation))
+	return tiff
+}
+
+// jpegSegment - сегмент JPEG с маркером marker
+func jpegSegment(marker byte, payload []byte) []byte {
+	segment := []byte{0xFF, marker, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+// jpegWithMetadata - закодированный JPEG и он же с сегментами EXIF, XMP и комментарием после SOI
+func jpegWithMetadata(t *testing.T, orientation int) (plain, withMetadata []byte) {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, testImage(8, 8), &jpeg.Options{Quality: 90}); err != nil {
+		t.Fatalf("jpeg.Encode: %v", err)
+	}
+	plain = buf.Bytes()
+
+	withMetadata = append([]byte{}, plain[:2]...)
+	withMetadata = append(withMetadata, jpegSegment(0xE1, append([]byte("Exif\x00\x00"), exifTIFF(binary.BigEndian, orientation)...))...)
+	withMetadata = append(withMetadata, jpegSegment(0xE1, []byte("http://ns.adobe.com/xap/1.0/\x00<x:xmpmeta/>"))...)
+	withMetadata = append(withMetadata, jpegSegment(0xFE, []byte("camera comment"))...)
+	withMetadata = append(withMetadata, plain[2:]...)
+	return plain, withMetadata
+}
+
+// pngChunk - чанк PNG с корректным CRC
+func pngChunk(kind string, payload []byte) []byte {
+	chunk := make([]byte, 8, 12+len(payload))
+	binary.BigEndian.PutUint32(chunk, uint32(len(payload)))
+	copy(chunk[4:], kind)
+	chunk = append(chunk, payload...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+// pngWithMetadata - закодированный PNG и он же с чанками eXIf, tEXt и tIME перед IEND
+func pngWithMetadata(t *testing.T) (plain, withMetadata []byte) {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage(8, 8)); err != nil {
+		t.Fatalf("png.Encode: %v", err)
+	}
+	plain = buf.Bytes()
+
+	iend := len(plain) - 12
+	withMetadata = append([]byte{}, plain[:iend]...)
+	withMetadata = append(withMetadata, pngChunk("eXIf", exifTIFF(binary.LittleEndian, 6))...)
+	withMetadata = append(withMetadata, pngChunk("tEXt", []byte("Author\x00someone"))...)
+	withMetadata = append(withMetadata, pngChunk("tIME", []byte{0x07, 0xE9, 1, 2, 3, 4, 5})...)
+	withMetadata = append(withMetadata, plain[iend:]...)
+	return plain, withMetadata
+}
+
+// webpChunk - чанк RIFF с выравниванием по чётной границе
+func webpChunk(fourCC string, payload []byte) []byte {
+	chunk := make([]byte, 8, 8+len(payload)+1)
+	copy(chunk, fourCC)
+	binary.LittleEndian.PutUint32(chunk[4:], uint32(len(payload)))
+	chunk = append(chunk, payload...)
+	if len(payload)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
+
+// webpFile - контейнер WebP из чанков (данные изображения не декодируются)
+func webpFile(chunks ...[]byte) []byte {
+	file := []byte("RIFF\x00\x00\x00\x00WEBP")
+	for _, chunk := range chunks {
+		file = append(file, chunk...)
+	}
+	binary.LittleEndian.PutUint32(file[4:], uint32(len(file)-8))
+	return file
+}
+
+func TestStripMetadata(t *testing.T) {
+	plainJPEG, metaJPEG := jpegWithMetadata(t, 6)
+	plainPNG, metaPNG := pngWithMetadata(t)
+
+	vp8x := make([]byte, 10)
+	vp8x[0] = 0x08 | 0x04 | 0x10 // EXIF, XMP и альфа-канал
+	vp8l := webpChunk("VP8L", []byte{0x2F, 1, 2, 3, 4})
+	metaWebP := webpFile(webpChunk("VP8X", vp8x), vp8l, webpChunk("EXIF", exifTIFF(binary.LittleEndian, 3)), webpChunk("XMP ", []byte("<x:xmpmeta/>")))
+	vp8x[0] = 0x10
+	plainWebP := webpFile(webpChunk("VP8X", vp8x), vp8l)
+
+	tests := []struct {
+		name        string
+		contentType string
+		data, want  []byte
+	}{
+		{"jpeg", "image/jpeg", metaJPEG, plainJPEG},
+		{"jpeg without metadata", "image/jpeg", plainJPEG, plainJPEG},
+		{"png", "image/png", metaPNG, plainPNG},
+		{"webp", "image/webp", metaWebP, plainWebP},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := StripMetadata(tt.data, tt.contentType)
+			if err != nil {
+				t.Fatalf("StripMetadata: %v", err)
+			}
+			// метаданные убраны, а данные изображения остались байт в байт
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("StripMetadata: got %d bytes, want %d bytes of the image without metadata", len(got), len(tt.want))
+			}
+		})
+	}
+
+	// пиксели после удаления метаданных не меняются
+	original, err := jpeg.Decode(bytes.NewReader(plainJPEG))
+	if err != nil {
+		t.Fatalf("decode original: %v", err)
+	}
+	stripped, _ := StripMetadata(metaJPEG, "image/jpeg")
+	decoded, err := jpeg.Decode(bytes.NewReader(stripped))
+	if err != nil {
+		t.Fatalf("decode stripped: %v", err)
+	}
+	if !bytes.Equal(original.(*image.YCbCr).Y, decoded.(*image.YCbCr).Y) {
+		t.Error("pixels changed after StripMetadata")
+	}
+}
+
+func TestStripMetadataMalformed(t *testing.T) {
+	_, metaJPEG := jpegWithMetadata(t, 6)
+	_, metaPNG := pngWithMetadata(t)
+
+	tests := []struct {
+		name        string
+		contentType string
+		data        []byte
+	}{
+		{"jpeg without SOI", "image/jpeg", []byte{0x00, 0x00, 0xFF, 0xE1}},
+		{"jpeg segment past the end", "image/jpeg", []byte{0xFF, 0xD8, 0xFF, 0xE1, 0xFF, 0xFF, 0x00}},
+		{"jpeg segment length below 2", "image/jpeg", []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00, 0x01, 0xFF, 0xDA}},
+		{"jpeg garbage between segments", "image/jpeg", []byte{0xFF, 0xD8, 0x12, 0x34, 0x00, 0x02}},
+		{"jpeg without scan", "image/jpeg", append([]byte{0xFF, 0xD8}, jpegSegment(0xE0, []byte("JFIF\x00"))...)},
+		{"png chunk past the end", "image/png", append(append([]byte{}, metaPNG[:8]...), 0xFF, 0xFF, 0xFF, 0xF0, 'I', 'D', 'A', 'T', 0, 0, 0, 0)},
+		{"png trailing bytes", "image/png", append(append([]byte{}, metaPNG...), 1, 2, 3)},
+		{"webp chunk past the end", "image/webp", webpFile([]byte("EXIF\xFF\xFF\xFF\x7F"))},
+		{"not webp", "image/webp", []byte("RIFF\x04\x00\x00\x00WAVE")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := StripMetadata(tt.data, tt.contentType); err == nil {
+				t.Error("StripMetadata: want an error for malformed data")
+			}
+		})
+	}
+
+	// обрезанные на любом байте файлы дают ошибку или результат, но не панику
+	for _, file := range []struct {
+		contentType string
+		data        []byte
+	}{{"image/jpeg", metaJPEG}, {"image/png", metaPNG}, {"image/webp", webpFile(webpChunk("VP8L", []byte{1, 2, 3}), webpChunk("EXIF", []byte{4, 5}))}} {
+		for n := 0; n < len(file.data); n++ {
+			StripMetadata(file.data[:n], file.contentType)
+			jpegOrientation(file.data[:n])
+		}
+	}
+}
+
+func TestOrientation(t *testing.T) {
+	for orientation := 1; orientation <= 8; orientation++ {
+		_, metaJPEG := jpegWithMetadata(t, orientation)
+		if got := jpegOrientation(metaJPEG); got != orientation {
+			t.Errorf("jpegOrientation = %d, want %d", got, orientation)
+		}
+		for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+			if got := tiffOrientation(exifTIFF(order, orientation)); got != orientation {
+				t.Errorf("tiffOrientation(%v) = %d, want %d", order, got, orientation)
+			}
+		}
+	}
+
+	valid := exifTIFF(binary.LittleEndian, 6)
+	ifdPastEnd := append([]byte{}, valid...)
+	binary.LittleEndian.PutUint32(ifdPastEnd[4:], 0xFFFFFFF0)
+	entriesPastEnd := append([]byte{}, valid...)
+	binary.LittleEndian.PutUint16(entriesPastEnd[8:], 0xFFFF)
+	binary.LittleEndian.PutUint16(entriesPastEnd[10:], 0x0100) // не Orientation: поиск идёт дальше конца
+	outOfRange := exifTIFF(binary.LittleEndian, 9)
+
+	tests := []struct {
+		name string
+		tiff []byte
+	}{
+		{"empty", nil},
+		{"short header", valid[:6]},
+		{"unknown byte order", append([]byte("XX"), valid[2:]...)},
+		{"ifd past the end", ifdPastEnd},
+		{"entries past the end", entriesPastEnd},
+		{"truncated entry", valid[:15]},
+		{"value out of range", outOfRange},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tiffOrientation(tt.tiff); got != 1 {
+				t.Errorf("tiffOrientation = %d, want 1", got)
+			}
+		})
+	}
+}
+
+func TestApplyOrientation(t *testing.T) {
+	// исходник 3x2:   1 2 3
+	//                 4 5 6
+	// ожидаемые изображения построчно, по значению R
+	tests := []struct {
+		orientation int
+		want        [][]uint8
+	}{
+		{1, [][]uint8{{1, 2, 3}, {4, 5, 6}}},
+		{2, [][]uint8{{3, 2, 1}, {6, 5, 4}}},
+		{3, [][]uint8{{6, 5, 4}, {3, 2, 1}}},
+		{4, [][]uint8{{4, 5, 6}, {1, 2, 3}}},
+		{5, [][]uint8{{1, 4}, {2, 5}, {3, 6}}},
+		{6, [][]uint8{{4, 1}, {5, 2}, {6, 3}}},
+		{7, [][]uint8{{6, 3}, {5, 2}, {4, 1}}},
+		{8, [][]uint8{{3, 6}, {2, 5}, {1, 4}}},
+	}
+	for _, tt := range tests {
+		src := testImage(3, 2)
+		got := applyOrientation(src, tt.orientation)
+
+		bounds := got.Bounds()
+		if bounds.Dx() != len(tt.want[0]) || bounds.Dy() != len(tt.want) {
+			t.Errorf("orientation %d: size %dx%d, want %dx%d", tt.orientation, bounds.Dx(), bounds.Dy(), len(tt.want[0]), len(tt.want))
+			continue
+		}
+		for y, row := range tt.want {
+			for x, want := range row {
+				r, _, _, a := got.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+				if uint8(r>>8) != want || a != 0xFFFF {
+					t.Errorf("orientation %d: pixel (%d,%d) = %d, want %d", tt.orientation, x, y, r>>8, want)
+				}
+			}
+		}
+	}
+}
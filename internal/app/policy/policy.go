@@ -0,0 +1,48 @@
+package policy
+
+import "errors"
+
+// Правила доступа к ресурсам API в одном месте: обработчики и middleware только
+// определяют, кто выполняет запрос (Subject), и спрашивают здесь, можно ли.
+
+// Роли пользователей (ds.User.Role)
+const (
+	RoleGuest     = "guest"
+	RoleCreator   = "creator"
+	RoleModerator = "moderator"
+)
+
+var (
+	// ErrForbidden - у субъекта нет прав на действие
+	ErrForbidden = errors.New("access denied")
+	// ErrModeratorRequired - действие доступно только модератору
+	ErrModeratorRequired = errors.New("moderator access required")
+)
+
+// Subject - кто выполняет запрос: пользователь из JWT или гость по cookie черновика
+type Subject struct {
+	UserID int
+	Role   string
+}
+
+// IsModerator - модератор видит и меняет чужие заявки и каталог
+func (s Subject) IsModerator() bool {
+	return s.Role == RoleModerator
+}
+
+// CanAccessRequestShip - заявку читают и меняют только её владелец и модераторы
+func CanAccessRequestShip(s Subject, ownerID int) error {
+	if s.IsModerator() || (s.UserID != 0 && s.UserID == ownerID) {
+		return nil
+	}
+	return ErrForbidden
+}
+
+// CanMutateCatalog - каталог кораблей (корабли, их изображения и галерея),
+// терминалы и причалы меняют только модераторы
+func CanMutateCatalog(s Subject) error {
+	if s.IsModerator() {
+		return nil
+	}
+	return ErrModeratorRequired
+}
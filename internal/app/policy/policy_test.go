@@ -0,0 +1,55 @@
+package policy
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCanAccessRequestShip(t *testing.T) {
+	const ownerID = 7
+
+	tests := []struct {
+		name    string
+		subject Subject
+		want    error
+	}{
+		{"guest", Subject{}, ErrForbidden},
+		{"guest role with id", Subject{UserID: 3, Role: RoleGuest}, ErrForbidden},
+		{"creator owner", Subject{UserID: ownerID, Role: RoleCreator}, nil},
+		{"creator non-owner", Subject{UserID: 8, Role: RoleCreator}, ErrForbidden},
+		{"moderator", Subject{UserID: 9, Role: RoleModerator}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CanAccessRequestShip(tt.subject, ownerID); !errors.Is(err, tt.want) {
+				t.Errorf("CanAccessRequestShip(%+v, %d) = %v, want %v", tt.subject, ownerID, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanAccessRequestShipWithoutOwner(t *testing.T) {
+	// нулевой владелец не совпадает с нулевым субъектом
+	if err := CanAccessRequestShip(Subject{}, 0); !errors.Is(err, ErrForbidden) {
+		t.Errorf("CanAccessRequestShip(guest, 0) = %v, want %v", err, ErrForbidden)
+	}
+}
+
+func TestCanMutateCatalog(t *testing.T) {
+	tests := []struct {
+		name    string
+		subject Subject
+		want    error
+	}{
+		{"guest", Subject{}, ErrModeratorRequired},
+		{"creator", Subject{UserID: 7, Role: RoleCreator}, ErrModeratorRequired},
+		{"moderator", Subject{UserID: 9, Role: RoleModerator}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CanMutateCatalog(tt.subject); !errors.Is(err, tt.want) {
+				t.Errorf("CanMutateCatalog(%+v) = %v, want %v", tt.subject, err, tt.want)
+			}
+		})
+	}
+}
@@ -1,65 +1,46 @@
 package repository
 
 import (
-	"context"
 	"fmt"
-	"time"
 
-	"github.com/go-redis/redis/v8"
+	"loading_time/internal/app/calculator"
+
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-// Repository — централизованный репозиторий с DB, Redis и конфигом JWT.
+// Repository — централизованный репозиторий с DB (сессии - в session.Store).
 type Repository struct {
-	db          *gorm.DB
-	redisClient *redis.Client
-	jwtKey      string
+	db         *gorm.DB
+	calculator calculator.LoadingTimeCalculator
 }
 
 // New — инициализация репозитория.
 // postgresDSN — строка подключения к Postgres (DSN)
-// redisAddr — "host:port", redisPass — пароль (может быть "")
-// jwtKey — секрет для подписи JWT
-func New(postgresDSN, redisAddr, redisPass, jwtKey string) (*Repository, error) {
+// calc — стратегия расчёта времени погрузки (nil — формула по умолчанию)
+func New(postgresDSN string, calc calculator.LoadingTimeCalculator) (*Repository, error) {
 	db, err := gorm.Open(postgres.Open(postgresDSN), &gorm.Config{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to open postgres: %w", err)
 	}
 
-	rdb := redis.NewClient(&redis.Options{
-		Addr:     redisAddr,
-		Password: redisPass,
-		DB:       0,
-	})
-
-	// проверка соединения с Redis
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-	if err := rdb.Ping(ctx).Err(); err != nil {
-		// не фаталим — но возвращаем ошибку, чтобы ты знала
-		return nil, fmt.Errorf("redis ping failed: %w", err)
+	if calc == nil {
+		calc = calculator.DefaultCalculator{}
 	}
 
 	repo := &Repository{
-		db:          db,
-		redisClient: rdb,
-		jwtKey:      jwtKey,
+		db:         db,
+		calculator: calc,
 	}
 	return repo, nil
 }
 
-// Redis возвращает клиент Redis
-func (r *Repository) Redis() *redis.Client {
-	return r.redisClient
-}
-
 // DB возвращает *gorm.DB (может быть полезно)
 func (r *Repository) DB() *gorm.DB {
 	return r.db
 }
 
-// JWTKey возвращает секретный ключ для подписи токенов
-func (r *Repository) JWTKey() string {
-	return r.jwtKey
+// Calculator возвращает стратегию расчёта времени погрузки
+func (r *Repository) Calculator() calculator.LoadingTimeCalculator {
+	return r.calculator
 }
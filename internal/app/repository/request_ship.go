@@ -1,16 +1,34 @@
 package repository
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"loading_time/internal/app/calculator"
 	"loading_time/internal/app/ds"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+var (
+	// ErrNotRequestOwner - удалить заявку может только её владелец
+	ErrNotRequestOwner = errors.New("only the owner can delete the request")
+	// ErrNotPurgeable - окончательно удалить можно только удалённую заявку, срок хранения которой истёк
+	ErrNotPurgeable = errors.New("request can be purged only after it was deleted and the retention period has passed")
+	// ErrCountLimit - число контейнеров или экземпляров корабля вне допустимых пределов
+	ErrCountLimit = fmt.Errorf("container counts must be between 0 and %d, ship counts between 1 and %d",
+		ds.MaxContainersCount, ds.MaxShipsCount)
+)
+
+// GetRequestShip - заявка с кораблями, владельцем и модератором; удалённые заявки не находятся
 func (r *Repository) GetRequestShip(id int) (ds.RequestShip, error) {
 	request_ship := ds.RequestShip{}
 	// обязательно проверяем ошибки, и если они появились - передаем выше, то есть хендлеру
-	err := r.db.Preload("Ships.Ship").Preload("User").Where("id = ?", id).First(&request_ship).Error
+	err := r.db.Preload("Ships.Ship").Preload("User").Preload("Moderator").Preload("Berth").
+		Where("request_ship_id = ? AND status != ?", id, ds.StatusDeleted).
+		First(&request_ship).Error
 	if err != nil {
 		return ds.RequestShip{}, err
 	}
@@ -22,7 +40,7 @@ func (r *Repository) GetOrCreateUserDraft(userID int) (ds.RequestShip, error) {
 	var requestShip ds.RequestShip
 
 	// Ищем существующий черновик для данного пользователя
-	err := r.db.Preload("Ships.Ship").Preload("User").Where("status = ? AND user_id = ?", "черновик", userID).First(&requestShip).Error
+	err := r.db.Preload("Ships.Ship").Preload("User").Preload("Berth").Where("status = ? AND user_id = ?", ds.StatusDraft, userID).First(&requestShip).Error
 	if err == nil {
 		return requestShip, nil // черновик найден
 	}
@@ -32,7 +50,7 @@ func (r *Repository) GetOrCreateUserDraft(userID int) (ds.RequestShip, error) {
 
 	// Создаем новый черновик
 	requestShip = ds.RequestShip{
-		Status:       "черновик",
+		Status:       ds.StatusDraft,
 		UserID:       userID,
 		CreationDate: time.Now(),
 	}
@@ -45,159 +63,597 @@ func (r *Repository) GetOrCreateUserDraft(userID int) (ds.RequestShip, error) {
 	return requestShip, nil
 }
 
+// MergeGuestDraft - перенести черновик гостя в черновик пользователя после входа.
+// Корабли суммируются, контейнеры и комментарий переносятся, если у пользователя они не заданы.
+// Черновик гостя и сам гость после переноса удаляются.
+func (r *Repository) MergeGuestDraft(guestToken string, userID int) error {
+	guest, err := r.getGuestUser(guestToken)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var guestDraft ds.RequestShip
+	err = r.db.Preload("Ships").Where("status = ? AND user_id = ?", ds.StatusDraft, guest.UserID).First(&guestDraft).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	hasGuestDraft := err == nil
+
+	var userDraft ds.RequestShip
+	if hasGuestDraft {
+		userDraft, err = r.GetOrCreateUserDraft(userID)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		if hasGuestDraft {
+			for _, guestShip := range guestDraft.Ships {
+				shipID := guestShip.ShipID
+				oldCount := 0
+
+				var existing ds.ShipInRequest
+				err := tx.Where("request_ship_id = ? AND ship_id = ?", userDraft.RequestShipID, shipID).First(&existing).Error
+				if err == nil {
+					oldCount = existing.ShipsCount
+					existing.ShipsCount += guestShip.ShipsCount
+					err = tx.Save(&existing).Error
+				} else if errors.Is(err, gorm.ErrRecordNotFound) {
+					err = tx.Create(&ds.ShipInRequest{
+						RequestShipID: userDraft.RequestShipID,
+						ShipID:        shipID,
+						ShipsCount:    guestShip.ShipsCount,
+					}).Error
+				}
+				if err != nil {
+					return err
+				}
+
+				err = recordRequestShipEvent(tx, userDraft.RequestShipID, userID, ds.EventShipAdded, &shipID,
+					map[string]int{"ships_count": oldCount}, map[string]int{"ships_count": oldCount + guestShip.ShipsCount})
+				if err != nil {
+					return err
+				}
+			}
+
+			if userDraft.Containers20ftCount == 0 && userDraft.Containers40ftCount == 0 {
+				if err := tx.Model(&ds.RequestShip{}).Where("request_ship_id = ?", userDraft.RequestShipID).Updates(map[string]interface{}{
+					"containers_20ft_count": guestDraft.Containers20ftCount,
+					"containers_40ft_count": guestDraft.Containers40ftCount,
+					"comment":               guestDraft.Comment,
+				}).Error; err != nil {
+					return err
+				}
+			}
+
+			if err := tx.Where("request_ship_id = ?", guestDraft.RequestShipID).Delete(&ds.ShipInRequest{}).Error; err != nil {
+				return err
+			}
+		}
+
+		// гостевые заявки в других статусах не появляются, поэтому удаляем всё, что за ним числится
+		guestRequests := tx.Model(&ds.RequestShip{}).Select("request_ship_id").Where("user_id = ?", guest.UserID)
+		if err := tx.Where("actor_id = ? OR request_ship_id IN (?)", guest.UserID, guestRequests).Delete(&ds.RequestShipEvent{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", guest.UserID).Delete(&ds.RequestShip{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&ds.User{}, guest.UserID).Error
+	})
+	if err != nil || !hasGuestDraft {
+		return err
+	}
+
+	// пересчитываем время погрузки объединённого черновика
+	merged, err := r.GetOrCreateUserDraft(userID)
+	if err != nil {
+		return err
+	}
+	loadingTime, err := r.CalculateLoadingTime(merged.RequestShipID, merged.Containers20ftCount, merged.Containers40ftCount)
+	if err != nil {
+		return err
+	}
+	return r.UpdateRequestShipLoadingTime(merged.RequestShipID, loadingTime)
+}
+
 // AddShipToRequestShip - добавить корабль в заявку через ORM
-func (r *Repository) AddShipToRequestShip(requestShipID, shipID int) error {
+func (r *Repository) AddShipToRequestShip(requestShipID, shipID, actorID int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return addShipToRequestShip(tx, requestShipID, shipID, 1, actorID)
+	})
+}
+
+// AddShipsToRequestShip - добавить в заявку набор кораблей (например, рекомендацию) целиком:
+// в одной транзакции, так что при ошибке заявка остаётся без изменений
+func (r *Repository) AddShipsToRequestShip(requestShipID int, ships []ds.ShipInRequest, actorID int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, ship := range ships {
+			if err := addShipToRequestShip(tx, requestShipID, ship.ShipID, ship.ShipsCount, actorID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// addShipToRequestShip - увеличить число кораблей shipID в заявке на count в рамках транзакции tx
+func addShipToRequestShip(tx *gorm.DB, requestShipID, shipID, count, actorID int) error {
+	if err := lockDraftRequestShip(tx, requestShipID); err != nil {
+		return err
+	}
+	if err := checkShipActive(tx, shipID); err != nil {
+		return err
+	}
+
 	// Сначала проверяем, есть ли уже такой корабль в заявке
 	var existingShip ds.ShipInRequest
-	err := r.db.Where("request_ship_id = ? AND ship_id = ?", requestShipID, shipID).First(&existingShip).Error
+	err := tx.Where("request_ship_id = ? AND ship_id = ?", requestShipID, shipID).First(&existingShip).Error
 
+	oldCount := 0
 	if err == nil {
 		// Корабль уже есть в заявке - увеличиваем количество
-		existingShip.ShipsCount++
-		return r.db.Save(&existingShip).Error
+		oldCount = existingShip.ShipsCount
+		if oldCount+count > ds.MaxShipsCount {
+			return ErrCountLimit
+		}
+		existingShip.ShipsCount += count
+		err = tx.Save(&existingShip).Error
+	} else if errors.Is(err, gorm.ErrRecordNotFound) {
+		if count > ds.MaxShipsCount {
+			return ErrCountLimit
+		}
+		err = tx.Create(&ds.ShipInRequest{
+			RequestShipID: requestShipID,
+			ShipID:        shipID,
+			ShipsCount:    count,
+		}).Error
 	}
-
-	shipInRequest := ds.ShipInRequest{
-		RequestShipID: requestShipID,
-		ShipID:        shipID,
-		ShipsCount:    1,
+	if err != nil {
+		return err
 	}
-	return r.db.Create(&shipInRequest).Error
+
+	return recordRequestShipEvent(tx, requestShipID, actorID, ds.EventShipAdded, &shipID,
+		map[string]int{"ships_count": oldCount}, map[string]int{"ships_count": oldCount + count})
 }
 
 // RemoveShipFromRequestShip — удалить корабль из заявки
-func (r *Repository) RemoveShipFromRequestShip(requestShipID, shipID int) error {
-	return r.db.
-		Where("request_ship_id = ? AND ship_id = ?", requestShipID, shipID).
-		Delete(&ds.ShipInRequest{}).
-		Error
+func (r *Repository) RemoveShipFromRequestShip(requestShipID, shipID, actorID int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockDraftRequestShip(tx, requestShipID); err != nil {
+			return err
+		}
+
+		var existingShip ds.ShipInRequest
+		err := tx.Where("request_ship_id = ? AND ship_id = ?", requestShipID, shipID).First(&existingShip).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil // удалять нечего
+		}
+		if err != nil {
+			return err
+		}
+
+		err = tx.
+			Where("request_ship_id = ? AND ship_id = ?", requestShipID, shipID).
+			Delete(&ds.ShipInRequest{}).
+			Error
+		if err != nil {
+			return err
+		}
+
+		return recordRequestShipEvent(tx, requestShipID, actorID, ds.EventShipRemoved, &shipID,
+			map[string]int{"ships_count": existingShip.ShipsCount}, nil)
+	})
 }
 
-// логическое удаление заявки через SQL
-func (r *Repository) DeleteRequestShipSQL(requestShipID int) error {
-	return r.db.Model(&ds.RequestShip{}).
+// lockRequestShip - заявка с блокировкой строки (FOR UPDATE) до конца транзакции tx
+func lockRequestShip(tx *gorm.DB, requestShipID int) (ds.RequestShip, error) {
+	var requestShip ds.RequestShip
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
 		Where("request_ship_id = ?", requestShipID).
-		Update("status", "удалён").Error
+		First(&requestShip).Error
+	return requestShip, err
 }
 
-// GetRequestShipExcludingDeleted - получить заявку исключая удаленные (через ORM)
-func (r *Repository) GetRequestShipExcludingDeleted(id int) (ds.RequestShip, error) {
+// lockDraftRequestShip - заблокировать заявку перед изменением её кораблей.
+// Менять корабли можно только в черновике, иначе - *ds.IllegalTransitionError.
+func lockDraftRequestShip(tx *gorm.DB, requestShipID int) error {
+	requestShip, err := lockRequestShip(tx, requestShipID)
+	if err != nil {
+		return err
+	}
+	// редактирование - переход статуса в самого себя, он разрешён только черновику
+	return requestShip.Status.CheckTransition(requestShipID, requestShip.Status)
+}
+
+// DeleteRequestShipSQL - логическое удаление черновика его владельцем (статус "удалён" и дата удаления).
+// Окончательно заявка удаляется PurgeRequestShip по истечении срока хранения.
+func (r *Repository) DeleteRequestShipSQL(requestShipID, ownerID int) error {
 	var requestShip ds.RequestShip
-	err := r.db.Preload("Ships.Ship").Preload("User").Where("request_ship_id = ? AND status != ?", id, "удалён").First(&requestShip).Error // добавили Preload("User")
+	err := r.db.Select("request_ship_id", "user_id").
+		Where("request_ship_id = ? AND status != ?", requestShipID, ds.StatusDeleted).
+		First(&requestShip).Error
 	if err != nil {
-		return ds.RequestShip{}, err
+		return err
 	}
-	return requestShip, nil
+	if requestShip.UserID != ownerID {
+		return ErrNotRequestOwner
+	}
+
+	return r.transitionRequestShip(requestShipID, ownerID, ds.StatusDeleted, map[string]interface{}{
+		"deletion_date": time.Now(),
+	})
 }
 
-// CalculateLoadingTime - рассчитывает время погрузки по формуле
-func (r *Repository) CalculateLoadingTime(requestShipID, containers20ft, containers40ft int) (float64, error) {
-	// Получаем заявку с кораблями
+// GetRequestShipOwnerID - владелец заявки для проверки доступа; удалённые заявки тоже находятся,
+// чтобы владелец получил 404 от обработчика, а не 403
+func (r *Repository) GetRequestShipOwnerID(requestShipID int) (int, error) {
 	var requestShip ds.RequestShip
-	err := r.db.Preload("Ships.Ship").Where("request_ship_id = ?", requestShipID).First(&requestShip).Error
+	err := r.db.Select("request_ship_id", "user_id").Where("request_ship_id = ?", requestShipID).First(&requestShip).Error
 	if err != nil {
 		return 0, err
 	}
+	return requestShip.UserID, nil
+}
+
+// PurgeRequestShip - окончательно удалить заявку вместе с кораблями и историей.
+// Только для удалённых заявок, у которых прошло retention с момента удаления.
+func (r *Repository) PurgeRequestShip(requestShipID int, retention time.Duration) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var requestShip ds.RequestShip
+		if err := tx.Where("request_ship_id = ?", requestShipID).First(&requestShip).Error; err != nil {
+			return err
+		}
+		if requestShip.Status != ds.StatusDeleted || requestShip.DeletionDate == nil ||
+			requestShip.DeletionDate.After(time.Now().Add(-retention)) {
+			return ErrNotPurgeable
+		}
+		return purgeRequestShip(tx, requestShipID)
+	})
+}
 
-	// Рассчитываем общее количество кранов
-	totalCranes := 0
-	for _, shipInRequest := range requestShip.Ships {
-		totalCranes += shipInRequest.Ship.Cranes * shipInRequest.ShipsCount
+// PurgeExpiredRequestShips - окончательно удалить все заявки, удалённые раньше deletedBefore.
+// При dryRun только возвращает их номера. Принимает *gorm.DB, чтобы команду purge
+// можно было запускать без Redis.
+func PurgeExpiredRequestShips(db *gorm.DB, deletedBefore time.Time, dryRun bool) ([]int, error) {
+	var ids []int
+	err := db.Model(&ds.RequestShip{}).
+		Where("status = ? AND deletion_date IS NOT NULL AND deletion_date <= ?", ds.StatusDeleted, deletedBefore).
+		Order("request_ship_id").
+		Pluck("request_ship_id", &ids).Error
+	if err != nil || dryRun {
+		return ids, err
 	}
 
-	if totalCranes == 0 {
-		return 0, nil
+	for i, id := range ids {
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			return purgeRequestShip(tx, id)
+		}); err != nil {
+			return ids[:i], err
+		}
 	}
+	return ids, nil
+}
 
-	// общее время = (20ft * 2 + 40ft * 3) / количество кранов
-	totalContainerTime := float64(containers20ft)*2 + float64(containers40ft)*3
-	return totalContainerTime / float64(totalCranes), nil
+// purgeRequestShip - удаление заявки и зависимых записей в транзакции tx
+func purgeRequestShip(tx *gorm.DB, requestShipID int) error {
+	if err := tx.Where("request_ship_id = ?", requestShipID).Delete(&ds.RequestShipEvent{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("request_ship_id = ?", requestShipID).Delete(&ds.ShipInRequest{}).Error; err != nil {
+		return err
+	}
+	return tx.Delete(&ds.RequestShip{}, requestShipID).Error
 }
 
-// UpdateRequestShipFields - обновляет поля заявки и рассчитывает время погрузки
-func (r *Repository) UpdateRequestShipFields(requestShipID, containers20ft, containers40ft int, comment string) error {
-	// Рассчитываем время погрузки
-	loadingTime, err := r.CalculateLoadingTime(requestShipID, containers20ft, containers40ft)
+// CalculateLoadingTime - рассчитывает время погрузки выбранной в конфиге стратегией
+func (r *Repository) CalculateLoadingTime(requestShipID, containers20ft, containers40ft int) (float64, error) {
+	// Получаем заявку с кораблями и причалом
+	var requestShip ds.RequestShip
+	err := r.db.Preload("Ships.Ship").Preload("Berth").Where("request_ship_id = ?", requestShipID).First(&requestShip).Error
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	// Обновляем заявку
-	return r.db.Model(&ds.RequestShip{}).Where("request_ship_id = ?", requestShipID).Updates(map[string]interface{}{
-		"containers_20ft_count": containers20ft,
-		"containers_40ft_count": containers40ft,
-		"comment":               comment,
-		"loading_time":          loadingTime,
-	}).Error
+	requestShip.Containers20ftCount = containers20ft
+	requestShip.Containers40ftCount = containers40ft
+	return r.calculator.Calculate(requestShip), nil
 }
 
-func (r *Repository) GetRequestShipsFiltered(startDate, endDate, status string) ([]ds.RequestShip, error) {
-	var requestShips []ds.RequestShip
-	query := r.db.Model(&ds.RequestShip{}).Where("status != ?", "deleted") // исключаем удалённые
+// SimulateLoading - симуляция погрузки заявки по кораблям; сохранённое время погрузки не меняется
+func (r *Repository) SimulateLoading(requestShipID int) (calculator.SimulationResult, error) {
+	var requestShip ds.RequestShip
+	err := r.db.Preload("Ships.Ship").Preload("Berth").Where("request_ship_id = ? AND status != ?", requestShipID, ds.StatusDeleted).First(&requestShip).Error
+	if err != nil {
+		return calculator.SimulationResult{}, err
+	}
+	return calculator.Simulate(requestShip, r.calculator)
+}
 
-	// Фильтры по дате
-	if startDate != "" {
-		query = query.Where("created_at >= ?", startDate)
+// loadingTimeColumns - время погрузки вместе со стратегией и её параметрами,
+// чтобы сохранённый результат можно было воспроизвести
+func (r *Repository) loadingTimeColumns(loadingTime float64) (map[string]interface{}, error) {
+	params, err := json.Marshal(r.calculator.Params())
+	if err != nil {
+		return nil, err
 	}
-	if endDate != "" {
-		query = query.Where("created_at <= ?", endDate)
+	return map[string]interface{}{
+		"loading_time":         loadingTime,
+		"calculation_strategy": r.calculator.Name(),
+		"calculation_params":   string(params),
+	}, nil
+}
+
+// UpdateRequestShipFields - обновляет поля заявки и рассчитывает время погрузки.
+// berthID: nil - причал не меняется, 0 - причал снимается, иначе - новый причал заявки.
+func (r *Repository) UpdateRequestShipFields(requestShipID, containers20ft, containers40ft int, comment string, berthID *int, actorID int) error {
+	if containers20ft < 0 || containers40ft < 0 || containers20ft > ds.MaxContainersCount || containers40ft > ds.MaxContainersCount {
+		return ErrCountLimit
 	}
 
-	// Фильтр по статусу
-	if status != "" {
-		query = query.Where("status = ?", status)
+	var requestShip ds.RequestShip
+	err := r.db.Preload("Ships.Ship").Preload("Berth").Where("request_ship_id = ?", requestShipID).First(&requestShip).Error
+	if err != nil {
+		return err
+	}
+
+	// краны нового причала сразу участвуют в расчёте
+	berthUpdate := map[string]interface{}{}
+	if berthID != nil {
+		if *berthID == 0 {
+			requestShip.Berth = nil
+			berthUpdate["berth_id"] = nil
+		} else {
+			berth, err := r.GetBerth(*berthID)
+			if err != nil {
+				return err
+			}
+			requestShip.Berth = &berth
+			berthUpdate["berth_id"] = berth.BerthID
+		}
 	}
 
-	err := query.Preload("Ships").Preload("User").Find(&requestShips).Error // добавили Preload("User")
-	return requestShips, err
+	// Рассчитываем время погрузки
+	requestShip.Containers20ftCount = containers20ft
+	requestShip.Containers40ftCount = containers40ft
+	updates, err := r.loadingTimeColumns(r.calculator.Calculate(requestShip))
+	if err != nil {
+		return err
+	}
+	updates["containers_20ft_count"] = containers20ft
+	updates["containers_40ft_count"] = containers40ft
+	updates["comment"] = comment
+	for column, value := range berthUpdate {
+		updates[column] = value
+	}
+
+	// Обновляем заявку (редактировать можно только черновик)
+	return r.transitionRequestShip(requestShipID, actorID, ds.StatusDraft, updates)
 }
 
 //_______________________________________________________________________________________________________
 
 // для REST API
 
-// UpdateRequestShipStatus - обновляет статус заявки
-func (r *Repository) UpdateRequestShipStatus(requestShipID int, status string) error {
-	updates := map[string]interface{}{
-		"status": status,
+// requestShipCheck - проверка содержимого заявки перед переходом статуса.
+// Выполняется в транзакции tx после блокировки строки заявки, поэтому корабли
+// заявки не могут измениться между проверкой и сменой статуса.
+type requestShipCheck func(tx *gorm.DB, requestShipID int) error
+
+// transitionRequestShip - переводит заявку в статус to по таблице переходов ds,
+// применяет updates и записывает изменение в историю от имени actorID.
+// Строка заявки блокируется до конца транзакции; checks выполняются после проверки перехода.
+// Недопустимый переход - *ds.IllegalTransitionError.
+func (r *Repository) transitionRequestShip(requestShipID, actorID int, to ds.RequestShipStatus, updates map[string]interface{}, checks ...requestShipCheck) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		current, err := lockRequestShip(tx, requestShipID)
+		if err != nil {
+			return err
+		}
+
+		if err := current.Status.CheckTransition(requestShipID, to); err != nil {
+			return err
+		}
+		for _, check := range checks {
+			if err := check(tx, requestShipID); err != nil {
+				return err
+			}
+		}
+
+		// в историю пишем только изменённые поля, без служебного статуса
+		newValue := map[string]interface{}{}
+		for column, value := range updates {
+			newValue[column] = value
+		}
+
+		updates["status"] = to
+		result := tx.Model(&ds.RequestShip{}).
+			Where("request_ship_id = ? AND status = ?", requestShipID, current.Status).
+			Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			// статус успел поменяться параллельным запросом
+			return &ds.IllegalTransitionError{RequestShipID: requestShipID, From: current.Status, To: to}
+		}
+
+		if current.Status == to {
+			return recordRequestShipEvent(tx, requestShipID, actorID, ds.EventFieldsUpdated, nil, map[string]interface{}{
+				"containers_20ft_count": current.Containers20ftCount,
+				"containers_40ft_count": current.Containers40ftCount,
+				"comment":               current.Comment,
+				"loading_time":          current.LoadingTime,
+				"berth_id":              current.BerthID,
+			}, newValue)
+		}
+
+		newValue["status"] = to
+		return recordRequestShipEvent(tx, requestShipID, actorID, ds.EventStatusChanged, nil,
+			map[string]interface{}{"status": current.Status}, newValue)
+	})
+}
+
+// checkRequestShipCapacity - контейнеры заявки должны поместиться на выбранные корабли.
+// При нехватке возвращает *ds.CapacityShortfallError с подсказками из каталога.
+func checkRequestShipCapacity(tx *gorm.DB, requestShipID int) error {
+	var requestShip ds.RequestShip
+	err := tx.Preload("Ships.Ship").Where("request_ship_id = ?", requestShipID).First(&requestShip).Error
+	if err != nil {
+		return err
 	}
 
-	if status == "сформирован" {
-		updates["formation_date"] = time.Now()
+	shortfall := requestShip.CheckCapacity()
+	if shortfall == nil {
+		return nil
 	}
 
-	return r.db.Model(&ds.RequestShip{}).
-		Where("request_ship_id = ?", requestShipID).
-		Updates(updates).Error
+	shortfall.Suggestions, err = suggestShipsForShortfall(tx, shortfall.ShortfallTEU(), shortfall.Shortfall40ft())
+	if err != nil {
+		return err
+	}
+	return shortfall
 }
 
-// CompleteRequestShip - завершает заявку (устанавливает модератора, статус и время)
-func (r *Repository) CompleteRequestShip(requestShipID, moderatorID int, status string, loadingTime float64) error {
-	return r.db.Model(&ds.RequestShip{}).
-		Where("request_ship_id = ?", requestShipID).
-		Updates(map[string]interface{}{
-			"status":          status,
-			"moderator_id":    moderatorID,
-			"completion_date": time.Now(),
-			"loading_time":    loadingTime,
-		}).Error
+// checkRequestShipBerthFit - корабли заявки должны помещаться у выбранного причала по длине,
+// ширине и осадке. При нарушениях возвращает *ds.BerthFitError с расшифровкой по кораблям.
+func checkRequestShipBerthFit(tx *gorm.DB, requestShipID int) error {
+	var requestShip ds.RequestShip
+	err := tx.Preload("Ships.Ship").Preload("Berth").Where("request_ship_id = ?", requestShipID).First(&requestShip).Error
+	if err != nil {
+		return err
+	}
+
+	if fitErr := requestShip.CheckBerthFit(); fitErr != nil {
+		return fitErr
+	}
+	return nil
 }
 
-// UpdateShipCountInRequest - обновляет количество кораблей в заявке
-func (r *Repository) UpdateShipCountInRequest(requestShipID, shipID, count int) error {
-	return r.db.Model(&ds.ShipInRequest{}).
-		Where("request_ship_id = ? AND ship_id = ?", requestShipID, shipID).
-		Update("ships_count", count).Error
+// checkRequestShipArchivedShips - сформировать можно только заявку без удалённых из каталога кораблей
+func checkRequestShipArchivedShips(tx *gorm.DB, requestShipID int) error {
+	var archived ds.ShipInRequest
+	err := tx.Joins("JOIN ships ON ships.ship_id = ships_in_request.ship_id").
+		Where("ships_in_request.request_ship_id = ? AND ships.is_active = ?", requestShipID, false).
+		First(&archived).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return &ds.ShipArchivedError{ShipID: archived.ShipID}
+}
+
+// SuggestShipsForShortfall - корабли каталога, закрывающие нехватку: самый маленький корабль,
+// которого хватает в одиночку, иначе крупнейшие корабли по убыванию вместимости
+func (r *Repository) SuggestShipsForShortfall(shortfallTEU float64, shortfall40ft int) ([]ds.Ship, error) {
+	return suggestShipsForShortfall(r.db, shortfallTEU, shortfall40ft)
+}
+
+func suggestShipsForShortfall(db *gorm.DB, shortfallTEU float64, shortfall40ft int) ([]ds.Ship, error) {
+	var ships []ds.Ship
+	err := db.Where("is_active = ?", true).Order("capacity, ship_id").Find(&ships).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ship := range ships {
+		if ship.Capacity >= shortfallTEU && ship.Containers >= shortfall40ft {
+			return []ds.Ship{ship}, nil
+		}
+	}
+
+	suggestions := []ds.Ship{}
+	teu, slots := 0.0, 0
+	for i := len(ships) - 1; i >= 0 && (teu < shortfallTEU || slots < shortfall40ft); i-- {
+		suggestions = append(suggestions, ships[i])
+		teu += ships[i].Capacity
+		slots += ships[i].Containers
+	}
+	return suggestions, nil
+}
+
+// UpdateRequestShipStatus - обновляет статус заявки
+func (r *Repository) UpdateRequestShipStatus(requestShipID int, status ds.RequestShipStatus, actorID int) error {
+	updates := map[string]interface{}{}
+
+	var checks []requestShipCheck
+	if status == ds.StatusFormed {
+		checks = []requestShipCheck{checkRequestShipArchivedShips, checkRequestShipBerthFit, checkRequestShipCapacity}
+		updates["formation_date"] = time.Now()
+	}
+
+	return r.transitionRequestShip(requestShipID, actorID, status, updates, checks...)
 }
 
-// DeleteRequestShip - полностью удалить заявку
-func (r *Repository) DeleteRequestShip(requestShipID int) error {
-	return r.db.Delete(&ds.RequestShip{}, requestShipID).Error
+// CompleteRequestShip - завершает или отклоняет заявку (устанавливает модератора, статус, время и причину отказа)
+func (r *Repository) CompleteRequestShip(requestShipID, moderatorID int, status ds.RequestShipStatus, loadingTime float64, rejectionReason string) error {
+	updates := map[string]interface{}{
+		"loading_time": loadingTime,
+	}
+	var checks []requestShipCheck
+	if status == ds.StatusCompleted {
+		checks = []requestShipCheck{checkRequestShipBerthFit, checkRequestShipCapacity}
+
+		var err error
+		updates, err = r.loadingTimeColumns(loadingTime)
+		if err != nil {
+			return err
+		}
+	}
+	updates["moderator_id"] = moderatorID
+	updates["completion_date"] = time.Now()
+	updates["rejection_reason"] = rejectionReason
+
+	return r.transitionRequestShip(requestShipID, moderatorID, status, updates, checks...)
+}
+
+// UpdateShipCountInRequest - обновляет количество кораблей в заявке
+func (r *Repository) UpdateShipCountInRequest(requestShipID, shipID, count, actorID int) error {
+	if count < 1 || count > ds.MaxShipsCount {
+		return ErrCountLimit
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockDraftRequestShip(tx, requestShipID); err != nil {
+			return err
+		}
+
+		var existingShip ds.ShipInRequest
+		err := tx.Where("request_ship_id = ? AND ship_id = ?", requestShipID, shipID).First(&existingShip).Error
+		if err != nil {
+			return err
+		}
+		// уменьшать количество удалённого корабля можно, увеличивать - нет
+		if count > existingShip.ShipsCount {
+			if err := checkShipActive(tx, shipID); err != nil {
+				return err
+			}
+		}
+
+		err = tx.Model(&ds.ShipInRequest{}).
+			Where("request_ship_id = ? AND ship_id = ?", requestShipID, shipID).
+			Update("ships_count", count).Error
+		if err != nil {
+			return err
+		}
+
+		return recordRequestShipEvent(tx, requestShipID, actorID, ds.EventShipCountUpdated, &shipID,
+			map[string]int{"ships_count": existingShip.ShipsCount}, map[string]int{"ships_count": count})
+	})
 }
 
 // UpdateRequestShipLoadingTime - сохраняет рассчитанное время погрузки
 func (r *Repository) UpdateRequestShipLoadingTime(requestShipID int, loadingTime float64) error {
+	updates, err := r.loadingTimeColumns(loadingTime)
+	if err != nil {
+		return err
+	}
 	return r.db.Model(&ds.RequestShip{}).
 		Where("request_ship_id = ?", requestShipID).
-		Update("loading_time", loadingTime).Error
+		Updates(updates).Error
 }
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"encoding/json"
+	"loading_time/internal/app/ds"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// recordRequestShipEvent - записывает изменение заявки в историю в рамках транзакции tx.
+// oldValue/newValue сериализуются в JSON; nil сохраняется как пустая строка.
+func recordRequestShipEvent(tx *gorm.DB, requestShipID, actorID int, eventType ds.RequestShipEventType, shipID *int, oldValue, newValue interface{}) error {
+	event := ds.RequestShipEvent{
+		RequestShipID: requestShipID,
+		ActorID:       actorID,
+		EventType:     eventType,
+		ShipID:        shipID,
+		CreatedAt:     time.Now(),
+	}
+
+	if oldValue != nil {
+		data, err := json.Marshal(oldValue)
+		if err != nil {
+			return err
+		}
+		event.OldValue = string(data)
+	}
+	if newValue != nil {
+		data, err := json.Marshal(newValue)
+		if err != nil {
+			return err
+		}
+		event.NewValue = string(data)
+	}
+
+	return tx.Create(&event).Error
+}
+
+// GetRequestShipEvents - история изменений заявки в хронологическом порядке
+func (r *Repository) GetRequestShipEvents(requestShipID int) ([]ds.RequestShipEvent, error) {
+	var events []ds.RequestShipEvent
+	err := r.db.Preload("Actor").
+		Where("request_ship_id = ?", requestShipID).
+		Order("created_at, event_id").
+		Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
@@ -0,0 +1,221 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"loading_time/internal/app/ds"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Поля сортировки списка заявок
+const (
+	SortByCreationDate   = "creation_date"
+	SortByFormationDate  = "formation_date"
+	SortByCompletionDate = "completion_date"
+	SortByLoadingTime    = "loading_time"
+)
+
+const (
+	DefaultRequestShipPageSize = 20
+	MaxRequestShipPageSize     = 100
+)
+
+// ErrInvalidCursor - курсор не выдан этим списком или выдан для другой сортировки
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// requestShipSortColumns - выражения сортировки; NULL заменяется наименьшим значением,
+// чтобы по выражению можно было строить курсор
+var requestShipSortColumns = map[string]string{
+	SortByCreationDate:   "request_ship.creation_date",
+	SortByFormationDate:  "COALESCE(request_ship.formation_date, '-infinity'::timestamp)",
+	SortByCompletionDate: "COALESCE(request_ship.completion_date, '-infinity'::timestamp)",
+	SortByLoadingTime:    "COALESCE(request_ship.loading_time, 0)",
+}
+
+// RequestShipFilter - фильтры, сортировка и страница списка заявок.
+// Нулевые значения означают "без фильтра"; удалённые заявки не попадают в список никогда.
+// Границы дат: From включительно, To - не включительно.
+type RequestShipFilter struct {
+	CreatedFrom, CreatedTo     *time.Time
+	FormedFrom, FormedTo       *time.Time
+	CompletedFrom, CompletedTo *time.Time
+	Statuses                   []ds.RequestShipStatus
+	UserID                     *int
+	ModeratorID                *int
+	ShipID                     *int
+
+	SortBy   string // SortBy*, по умолчанию SortByCreationDate
+	SortDesc bool
+	Limit    int    // по умолчанию DefaultRequestShipPageSize, не больше MaxRequestShipPageSize
+	Cursor   string // NextCursor предыдущей страницы
+}
+
+// RequestShipPage - страница списка заявок; Total - число заявок под фильтром без учёта страницы
+type RequestShipPage struct {
+	Items      []ds.RequestShip
+	Total      int64
+	NextCursor string // пусто на последней странице
+}
+
+// requestShipCursor - позиция последней заявки страницы: значение поля сортировки и номер заявки
+type requestShipCursor struct {
+	SortBy string          `json:"s"`
+	Desc   bool            `json:"d"`
+	Value  json.RawMessage `json:"v"`
+	ID     int             `json:"id"`
+}
+
+// ListRequestShips - список заявок по фильтру с курсорной пагинацией
+func (r *Repository) ListRequestShips(filter RequestShipFilter) (RequestShipPage, error) {
+	if filter.SortBy == "" {
+		filter.SortBy = SortByCreationDate
+	}
+	sortColumn, ok := requestShipSortColumns[filter.SortBy]
+	if !ok {
+		return RequestShipPage{}, fmt.Errorf("unknown sort field %q", filter.SortBy)
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultRequestShipPageSize
+	}
+	if filter.Limit > MaxRequestShipPageSize {
+		filter.Limit = MaxRequestShipPageSize
+	}
+
+	query := r.filteredRequestShips(filter)
+
+	var page RequestShipPage
+	if err := query.Session(&gorm.Session{}).Count(&page.Total).Error; err != nil {
+		return RequestShipPage{}, err
+	}
+
+	direction, compare := "ASC", ">"
+	if filter.SortDesc {
+		direction, compare = "DESC", "<"
+	}
+
+	if filter.Cursor != "" {
+		cursor, value, err := decodeRequestShipCursor(filter.Cursor, filter.SortBy, filter.SortDesc)
+		if err != nil {
+			return RequestShipPage{}, err
+		}
+		query = query.Where(fmt.Sprintf("(%s, request_ship.request_ship_id) %s (?, ?)", sortColumn, compare), value, cursor.ID)
+	}
+
+	// берём на одну запись больше, чтобы понять, есть ли следующая страница
+	err := query.
+		Order(fmt.Sprintf("%s %s, request_ship.request_ship_id %s", sortColumn, direction, direction)).
+		Limit(filter.Limit + 1).
+		Preload("Ships").Preload("User").Preload("Moderator").
+		Find(&page.Items).Error
+	if err != nil {
+		return RequestShipPage{}, err
+	}
+
+	if len(page.Items) > filter.Limit {
+		page.Items = page.Items[:filter.Limit]
+		page.NextCursor, err = encodeRequestShipCursor(page.Items[len(page.Items)-1], filter.SortBy, filter.SortDesc)
+		if err != nil {
+			return RequestShipPage{}, err
+		}
+	}
+	return page, nil
+}
+
+// filteredRequestShips - запрос с фильтрами без сортировки и страницы
+func (r *Repository) filteredRequestShips(filter RequestShipFilter) *gorm.DB {
+	query := r.db.Model(&ds.RequestShip{}).Where("request_ship.status != ?", ds.StatusDeleted) // исключаем удалённые
+
+	dateRanges := []struct {
+		column   string
+		from, to *time.Time
+	}{
+		{"request_ship.creation_date", filter.CreatedFrom, filter.CreatedTo},
+		{"request_ship.formation_date", filter.FormedFrom, filter.FormedTo},
+		{"request_ship.completion_date", filter.CompletedFrom, filter.CompletedTo},
+	}
+	for _, dateRange := range dateRanges {
+		if dateRange.from != nil {
+			query = query.Where(dateRange.column+" >= ?", *dateRange.from)
+		}
+		if dateRange.to != nil {
+			query = query.Where(dateRange.column+" < ?", *dateRange.to)
+		}
+	}
+
+	if len(filter.Statuses) > 0 {
+		query = query.Where("request_ship.status IN ?", filter.Statuses)
+	}
+	if filter.UserID != nil {
+		query = query.Where("request_ship.user_id = ?", *filter.UserID)
+	}
+	if filter.ModeratorID != nil {
+		query = query.Where("request_ship.moderator_id = ?", *filter.ModeratorID)
+	}
+	if filter.ShipID != nil {
+		query = query.Where("EXISTS (SELECT 1 FROM ships_in_request sir WHERE sir.request_ship_id = request_ship.request_ship_id AND sir.ship_id = ?)", *filter.ShipID)
+	}
+	return query
+}
+
+// requestShipSortValue - значение поля сортировки заявки в том же виде, что и выражение в requestShipSortColumns
+func requestShipSortValue(requestShip ds.RequestShip, sortBy string) interface{} {
+	switch sortBy {
+	case SortByFormationDate:
+		return requestShip.FormationDate
+	case SortByCompletionDate:
+		return requestShip.CompletionDate
+	case SortByLoadingTime:
+		return requestShip.LoadingTime
+	default:
+		return requestShip.CreationDate
+	}
+}
+
+func encodeRequestShipCursor(last ds.RequestShip, sortBy string, desc bool) (string, error) {
+	value, err := json.Marshal(requestShipSortValue(last, sortBy))
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(requestShipCursor{SortBy: sortBy, Desc: desc, Value: value, ID: last.RequestShipID})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeRequestShipCursor - курсор и значение поля сортировки для условия в запросе
+func decodeRequestShipCursor(encoded, sortBy string, desc bool) (requestShipCursor, interface{}, error) {
+	var cursor requestShipCursor
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return cursor, nil, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return cursor, nil, ErrInvalidCursor
+	}
+	if cursor.SortBy != sortBy || cursor.Desc != desc {
+		return cursor, nil, ErrInvalidCursor
+	}
+
+	if sortBy == SortByLoadingTime {
+		var value float64
+		if err := json.Unmarshal(cursor.Value, &value); err != nil {
+			return cursor, nil, ErrInvalidCursor
+		}
+		return cursor, value, nil
+	}
+
+	var value *time.Time
+	if err := json.Unmarshal(cursor.Value, &value); err != nil {
+		return cursor, nil, ErrInvalidCursor
+	}
+	if value == nil {
+		// NULL в выражении сортировки заменён на -infinity
+		return cursor, gorm.Expr("'-infinity'::timestamp"), nil
+	}
+	return cursor, *value, nil
+}
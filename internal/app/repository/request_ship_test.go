@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+
+	"loading_time/internal/app/calculator"
+	"loading_time/internal/app/ds"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newMockRepository - репозиторий поверх sqlmock: запросы к базе задаются ожиданиями mock
+func newMockRepository(t *testing.T) (*Repository, sqlmock.Sqlmock) {
+	t.Helper()
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("gorm.Open: %v", err)
+	}
+	return &Repository{db: db, calculator: calculator.DefaultCalculator{}}, mock
+}
+
+// expectLockedRequestShip - SELECT ... FOR UPDATE заявки, которая находится в статусе status
+func expectLockedRequestShip(mock sqlmock.Sqlmock, requestShipID int, status ds.RequestShipStatus) {
+	mock.ExpectQuery(`SELECT \* FROM "request_ship" WHERE request_ship_id = \$1 .*FOR UPDATE`).
+		WithArgs(requestShipID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"request_ship_id", "status"}).AddRow(requestShipID, status))
+}
+
+func TestShipMutationsRequireDraft(t *testing.T) {
+	mutations := map[string]func(r *Repository) error{
+		"add": func(r *Repository) error {
+			return r.AddShipToRequestShip(1, 5, 7)
+		},
+		"add many": func(r *Repository) error {
+			return r.AddShipsToRequestShip(1, []ds.ShipInRequest{{ShipID: 5, ShipsCount: 2}}, 7)
+		},
+		"remove": func(r *Repository) error {
+			return r.RemoveShipFromRequestShip(1, 5, 7)
+		},
+		"update count": func(r *Repository) error {
+			return r.UpdateShipCountInRequest(1, 5, 3, 7)
+		},
+	}
+
+	for name, mutate := range mutations {
+		for _, status := range []ds.RequestShipStatus{ds.StatusFormed, ds.StatusCompleted, ds.StatusRejected, ds.StatusDeleted} {
+			t.Run(name+" "+string(status), func(t *testing.T) {
+				repo, mock := newMockRepository(t)
+				mock.ExpectBegin()
+				expectLockedRequestShip(mock, 1, status)
+				mock.ExpectRollback()
+
+				err := mutate(repo)
+				var illegal *ds.IllegalTransitionError
+				if !errors.As(err, &illegal) {
+					t.Fatalf("err = %v, want *ds.IllegalTransitionError", err)
+				}
+				if illegal.From != status {
+					t.Errorf("IllegalTransitionError.From = %q, want %q", illegal.From, status)
+				}
+				if err := mock.ExpectationsWereMet(); err != nil {
+					t.Error(err)
+				}
+			})
+		}
+	}
+}
+
+func TestCompleteRequestShipChecksCapacityUnderLock(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	requestRow := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"request_ship_id", "status", "containers_40ft_count", "berth_id"}).AddRow(1, ds.StatusFormed, 10, nil)
+	}
+
+	// проверки идут в той же транзакции и после блокировки строки заявки
+	mock.ExpectBegin()
+	expectLockedRequestShip(mock, 1, ds.StatusFormed)
+	// checkRequestShipBerthFit: заявка без причала и без кораблей
+	mock.ExpectQuery(`SELECT \* FROM "request_ship" WHERE request_ship_id = \$1`).WillReturnRows(requestRow())
+	mock.ExpectQuery(`SELECT \* FROM "berths"`).WillReturnRows(sqlmock.NewRows([]string{"berth_id"}))
+	mock.ExpectQuery(`SELECT \* FROM "ships_in_request"`).WillReturnRows(sqlmock.NewRows([]string{"request_ship_id", "ship_id"}))
+	// checkRequestShipCapacity: 10 контейнеров 40ft не на что грузить
+	mock.ExpectQuery(`SELECT \* FROM "request_ship" WHERE request_ship_id = \$1`).WillReturnRows(requestRow())
+	mock.ExpectQuery(`SELECT \* FROM "ships_in_request"`).WillReturnRows(sqlmock.NewRows([]string{"request_ship_id", "ship_id"}))
+	mock.ExpectQuery(`SELECT \* FROM "ships" WHERE is_active`).WillReturnRows(sqlmock.NewRows([]string{"ship_id"}))
+	mock.ExpectRollback()
+
+	err := repo.CompleteRequestShip(1, 9, ds.StatusCompleted, 12, "")
+	var shortfall *ds.CapacityShortfallError
+	if !errors.As(err, &shortfall) {
+		t.Fatalf("err = %v, want *ds.CapacityShortfallError", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -1,39 +1,30 @@
 package repository
 
 import (
-	"fmt"
+	"loading_time/internal/app/calculator"
 	"loading_time/internal/app/ds"
-)
 
-func (r *Repository) GetShips() ([]ds.Ship, error) {
-	var ships []ds.Ship
-	err := r.db.Find(&ships).Error
-	if err != nil {
-		return nil, err
-	}
-	if len(ships) == 0 {
-		return nil, fmt.Errorf("массив пустой")
-	}
-
-	return ships, nil
-}
+	"gorm.io/gorm"
+)
 
+// GetShip - корабль каталога; удалённый корабль не находится (gorm.ErrRecordNotFound)
 func (r *Repository) GetShip(id int) (ds.Ship, error) {
 	ship := ds.Ship{}
-	err := r.db.Where("ship_id = ?", id).First(&ship).Error
+	err := r.db.Where("ship_id = ? AND is_active = ?", id, true).First(&ship).Error
 	if err != nil {
 		return ds.Ship{}, err
 	}
 	return ship, nil
 }
 
-func (r *Repository) GetShipsByName(name string) ([]ds.Ship, error) {
-	var ships []ds.Ship
-	err := r.db.Where("name ILIKE ?", "%"+name+"%").Find(&ships).Error
+// GetShipAny - корабль каталога, в том числе удалённый: для изменений каталога модератором
+func (r *Repository) GetShipAny(id int) (ds.Ship, error) {
+	ship := ds.Ship{}
+	err := r.db.Where("ship_id = ?", id).First(&ship).Error
 	if err != nil {
-		return nil, err
+		return ds.Ship{}, err
 	}
-	return ships, nil
+	return ship, nil
 }
 
 // CreateShip - создание корабля
@@ -50,3 +41,38 @@ func (r *Repository) UpdateShip(id int, ship *ds.Ship) error {
 func (r *Repository) DeleteShip(id int) error {
 	return r.db.Model(&ds.Ship{}).Where("ship_id = ?", id).Update("is_active", false).Error
 }
+
+// RestoreShip - вернуть удалённый корабль в каталог
+func (r *Repository) RestoreShip(id int) error {
+	result := r.db.Model(&ds.Ship{}).Where("ship_id = ?", id).Update("is_active", true)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// checkShipActive - *ds.ShipArchivedError, если корабль удалён из каталога
+func checkShipActive(tx *gorm.DB, shipID int) error {
+	var ship ds.Ship
+	if err := tx.Select("ship_id", "is_active").Where("ship_id = ?", shipID).First(&ship).Error; err != nil {
+		return err
+	}
+	if !ship.IsActive {
+		return &ds.ShipArchivedError{ShipID: shipID}
+	}
+	return nil
+}
+
+// RecommendFleet - подобрать набор активных кораблей каталога под груз (см. calculator.Recommend)
+func (r *Repository) RecommendFleet(req calculator.RecommendationRequest) (calculator.Recommendation, bool, error) {
+	var ships []ds.Ship
+	if err := r.db.Where("is_active = ?", true).Find(&ships).Error; err != nil {
+		return calculator.Recommendation{}, false, err
+	}
+
+	recommendation, ok := calculator.Recommend(ships, req, r.calculator)
+	return recommendation, ok, nil
+}
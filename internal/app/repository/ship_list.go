@@ -0,0 +1,296 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"loading_time/internal/app/ds"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultShipPageSize = 20
+	MaxShipPageSize     = 100
+)
+
+// ErrCursorWithOffset - курсор и смещение взаимоисключающие
+var ErrCursorWithOffset = errors.New("cursor and offset cannot be used together")
+
+// shipSortColumns - поля каталога, по которым можно фильтровать диапазоном и сортировать.
+// NULL заменяется нулём, чтобы по выражению можно было строить курсор.
+var shipSortColumns = map[string]string{
+	"name":       "ships.name",
+	"capacity":   "COALESCE(ships.capacity, 0)",
+	"length":     "COALESCE(ships.length, 0)",
+	"width":      "COALESCE(ships.width, 0)",
+	"draft":      "COALESCE(ships.draft, 0)",
+	"cranes":     "COALESCE(ships.cranes, 0)",
+	"containers": "COALESCE(ships.containers, 0)",
+	ShipSortRank: shipSearchRank, // только вместе с ShipFilter.Query
+}
+
+// IsShipSortField - можно ли сортировать каталог по полю
+func IsShipSortField(field string) bool {
+	_, ok := shipSortColumns[field]
+	return ok
+}
+
+// Range - числовой диапазон, границы включительно; nil - без ограничения
+type Range struct {
+	Min, Max *float64
+}
+
+// ShipSort - одно поле сортировки каталога
+type ShipSort struct {
+	Field string
+	Desc  bool
+}
+
+// ShipFilter - фильтры, сортировка и страница каталога кораблей.
+// Сортировка всегда дополняется ship_id, чтобы порядок был однозначным.
+type ShipFilter struct {
+	Name     string           // подстрока названия
+	Query    string           // полнотекстовый поиск по названию и описанию, см. ship_search.go
+	Ranges   map[string]Range // поле (см. shipSortColumns) -> диапазон
+	Inactive bool             // удалённые корабли вместо активных (для модераторов)
+
+	Sort   []ShipSort
+	Limit  int    // по умолчанию DefaultShipPageSize, не больше MaxShipPageSize
+	Offset int    // нельзя вместе с Cursor
+	Cursor string // NextCursor предыдущей страницы
+}
+
+// ShipPage - страница каталога; Total - число кораблей под фильтром без учёта страницы
+type ShipPage struct {
+	Items      []ds.Ship
+	Total      int64
+	NextCursor string // пусто на последней странице
+}
+
+// shipCursor - значения полей сортировки последнего корабля страницы
+type shipCursor struct {
+	Sort   string            `json:"s"`
+	Values []json.RawMessage `json:"v"`
+	ID     int               `json:"id"`
+}
+
+// ListShips - каталог кораблей по фильтру; единый запрос для HTML-страницы и API.
+// При полнотекстовом поиске без явной сортировки результаты упорядочены по релевантности.
+func (r *Repository) ListShips(filter ShipFilter) (ShipPage, error) {
+	if filter.Query != "" && len(filter.Sort) == 0 {
+		filter.Sort = []ShipSort{{Field: ShipSortRank, Desc: true}}
+	}
+	for _, sort := range filter.Sort {
+		if !IsShipSortField(sort.Field) {
+			return ShipPage{}, fmt.Errorf("unknown sort field %q", sort.Field)
+		}
+		if sort.Field == ShipSortRank && filter.Query == "" {
+			return ShipPage{}, ErrRankWithoutQuery
+		}
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultShipPageSize
+	}
+	if filter.Limit > MaxShipPageSize {
+		filter.Limit = MaxShipPageSize
+	}
+	if filter.Cursor != "" && filter.Offset > 0 {
+		return ShipPage{}, ErrCursorWithOffset
+	}
+
+	query, err := r.filteredShips(filter)
+	if err != nil {
+		return ShipPage{}, err
+	}
+
+	var page ShipPage
+	if err := query.Session(&gorm.Session{}).Count(&page.Total).Error; err != nil {
+		return ShipPage{}, err
+	}
+
+	if filter.Cursor != "" {
+		condition, args, err := shipCursorCondition(filter.Cursor, filter.Sort)
+		if err != nil {
+			return ShipPage{}, err
+		}
+		query = query.Where(condition, args...)
+	}
+
+	order := make([]string, 0, len(filter.Sort)+1)
+	for _, sort := range filter.Sort {
+		order = append(order, shipSortColumns[sort.Field]+sortDirection(sort.Desc))
+	}
+	order = append(order, "ships.ship_id")
+
+	// берём на одну запись больше, чтобы понять, есть ли следующая страница
+	query = query.Order(strings.Join(order, ", ")).
+		Offset(filter.Offset).
+		Limit(filter.Limit + 1)
+	if filter.Query != "" {
+		page.Items, err = findShipsWithMatches(query)
+	} else {
+		err = query.Find(&page.Items).Error
+	}
+	if err != nil {
+		return ShipPage{}, err
+	}
+
+	if len(page.Items) > filter.Limit {
+		page.Items = page.Items[:filter.Limit]
+		page.NextCursor, err = encodeShipCursor(page.Items[len(page.Items)-1], filter.Sort)
+		if err != nil {
+			return ShipPage{}, err
+		}
+	}
+	return page, nil
+}
+
+// filteredShips - запрос с фильтрами без сортировки и страницы
+func (r *Repository) filteredShips(filter ShipFilter) (*gorm.DB, error) {
+	query := r.db.Model(&ds.Ship{}).Where("ships.is_active = ?", !filter.Inactive)
+
+	if filter.Name != "" {
+		query = query.Where("ships.name ILIKE ?", "%"+filter.Name+"%")
+	}
+	if filter.Query != "" {
+		query = withShipSearch(query, filter.Query)
+	}
+	for field, bounds := range filter.Ranges {
+		column, ok := shipSortColumns[field]
+		if !ok || field == "name" || field == ShipSortRank {
+			return nil, fmt.Errorf("unknown range field %q", field)
+		}
+		if bounds.Min != nil {
+			query = query.Where(column+" >= ?", *bounds.Min)
+		}
+		if bounds.Max != nil {
+			query = query.Where(column+" <= ?", *bounds.Max)
+		}
+	}
+	return query, nil
+}
+
+func sortDirection(desc bool) string {
+	if desc {
+		return " DESC"
+	}
+	return ""
+}
+
+// shipSortKey - описание сортировки в курсоре, чтобы курсор нельзя было применить к другой сортировке
+func shipSortKey(sorts []ShipSort) string {
+	parts := make([]string, 0, len(sorts))
+	for _, sort := range sorts {
+		if sort.Desc {
+			parts = append(parts, "-"+sort.Field)
+		} else {
+			parts = append(parts, sort.Field)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// shipSortValue - значение поля сортировки корабля в том же виде, что и выражение в shipSortColumns
+func shipSortValue(ship ds.Ship, field string) interface{} {
+	switch field {
+	case "name":
+		return ship.Name
+	case "capacity":
+		return ship.Capacity
+	case "length":
+		return ship.Length
+	case "width":
+		return ship.Width
+	case "draft":
+		return ship.Draft
+	case "cranes":
+		return ship.Cranes
+	case ShipSortRank:
+		if ship.Match == nil {
+			return 0
+		}
+		return ship.Match.Rank
+	default:
+		return ship.Containers
+	}
+}
+
+func encodeShipCursor(last ds.Ship, sorts []ShipSort) (string, error) {
+	cursor := shipCursor{Sort: shipSortKey(sorts), ID: last.ShipID}
+	for _, sort := range sorts {
+		value, err := json.Marshal(shipSortValue(last, sort.Field))
+		if err != nil {
+			return "", err
+		}
+		cursor.Values = append(cursor.Values, value)
+	}
+
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// shipCursorCondition - условие "после курсора" для сортировки с разными направлениями:
+// (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ... OR (k1 = v1 AND ... AND ship_id > id)
+func shipCursorCondition(encoded string, sorts []ShipSort) (string, []interface{}, error) {
+	var cursor shipCursor
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", nil, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return "", nil, ErrInvalidCursor
+	}
+	if cursor.Sort != shipSortKey(sorts) || len(cursor.Values) != len(sorts) {
+		return "", nil, ErrInvalidCursor
+	}
+
+	columns := make([]string, 0, len(sorts)+1)
+	values := make([]interface{}, 0, len(sorts)+1)
+	compares := make([]string, 0, len(sorts)+1)
+	for i, sort := range sorts {
+		var value interface{}
+		if sort.Field == "name" {
+			var name string
+			err = json.Unmarshal(cursor.Values[i], &name)
+			value = name
+		} else {
+			var number float64
+			err = json.Unmarshal(cursor.Values[i], &number)
+			value = number
+		}
+		if err != nil {
+			return "", nil, ErrInvalidCursor
+		}
+
+		columns = append(columns, shipSortColumns[sort.Field])
+		values = append(values, value)
+		if sort.Desc {
+			compares = append(compares, "<")
+		} else {
+			compares = append(compares, ">")
+		}
+	}
+	columns = append(columns, "ships.ship_id")
+	values = append(values, cursor.ID)
+	compares = append(compares, ">")
+
+	var conditions []string
+	var args []interface{}
+	for i := range columns {
+		parts := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			parts = append(parts, columns[j]+" = ?")
+			args = append(args, values[j])
+		}
+		parts = append(parts, columns[i]+" "+compares[i]+" ?")
+		args = append(args, values[i])
+		conditions = append(conditions, "("+strings.Join(parts, " AND ")+")")
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")", args, nil
+}
@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"errors"
+	"loading_time/internal/app/ds"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrPhotoOrderMismatch - новый порядок галереи должен содержать каждую фотографию корабля ровно один раз
+var ErrPhotoOrderMismatch = errors.New("photo order must list every photo of the ship exactly once")
+
+// GetShipPhotos - галерея корабля в порядке отображения, IsPrimary отмечает Ship.PhotoURL
+func (r *Repository) GetShipPhotos(shipID int) ([]ds.ShipPhoto, error) {
+	var ship ds.Ship
+	if err := r.db.Where("ship_id = ?", shipID).First(&ship).Error; err != nil {
+		return nil, err
+	}
+
+	var photos []ds.ShipPhoto
+	err := r.db.Where("ship_id = ?", shipID).Order("position, photo_id").Find(&photos).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range photos {
+		photos[i].IsPrimary = photos[i].FileName == ship.PhotoURL
+	}
+	return photos, nil
+}
+
+// AddShipPhoto - добавить фотографию в конец галереи. Фотография становится основной
+// (Ship.PhotoURL), если makePrimary или у корабля ещё нет основной фотографии.
+func (r *Repository) AddShipPhoto(shipID int, fileName, caption string, makePrimary bool) (ds.ShipPhoto, error) {
+	photo := ds.ShipPhoto{
+		ShipID:    shipID,
+		FileName:  fileName,
+		Caption:   caption,
+		CreatedAt: time.Now(),
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var ship ds.Ship
+		if err := tx.Where("ship_id = ?", shipID).First(&ship).Error; err != nil {
+			return err
+		}
+		if err := ensureLegacyShipPhoto(tx, ship); err != nil {
+			return err
+		}
+
+		var maxPosition *int
+		if err := tx.Model(&ds.ShipPhoto{}).Where("ship_id = ?", shipID).
+			Select("MAX(position)").Scan(&maxPosition).Error; err != nil {
+			return err
+		}
+		if maxPosition != nil {
+			photo.Position = *maxPosition + 1
+		}
+		if err := tx.Create(&photo).Error; err != nil {
+			return err
+		}
+
+		if makePrimary || ship.PhotoURL == "" {
+			photo.IsPrimary = true
+			return tx.Model(&ds.Ship{}).Where("ship_id = ?", shipID).Update("photo_url", fileName).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return ds.ShipPhoto{}, err
+	}
+	return photo, nil
+}
+
+// UpdateShipPhotoCaption - изменить подпись фотографии
+func (r *Repository) UpdateShipPhotoCaption(shipID, photoID int, caption string) error {
+	result := r.db.Model(&ds.ShipPhoto{}).
+		Where("ship_id = ? AND photo_id = ?", shipID, photoID).
+		Update("caption", caption)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ReorderShipPhotos - задать порядок галереи списком photoIDs
+func (r *Repository) ReorderShipPhotos(shipID int, photoIDs []int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var existing []int
+		if err := tx.Model(&ds.ShipPhoto{}).Where("ship_id = ?", shipID).
+			Pluck("photo_id", &existing).Error; err != nil {
+			return err
+		}
+		if len(existing) != len(photoIDs) {
+			return ErrPhotoOrderMismatch
+		}
+
+		known := make(map[int]bool, len(existing))
+		for _, id := range existing {
+			known[id] = true
+		}
+		for _, id := range photoIDs {
+			if !known[id] {
+				return ErrPhotoOrderMismatch
+			}
+			delete(known, id) // повтор того же ID тоже ошибка
+		}
+
+		for position, id := range photoIDs {
+			if err := tx.Model(&ds.ShipPhoto{}).Where("photo_id = ?", id).
+				Update("position", position).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SetPrimaryShipPhoto - сделать фотографию галереи основной (Ship.PhotoURL)
+func (r *Repository) SetPrimaryShipPhoto(shipID, photoID int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var photo ds.ShipPhoto
+		if err := tx.Where("ship_id = ? AND photo_id = ?", shipID, photoID).First(&photo).Error; err != nil {
+			return err
+		}
+		return tx.Model(&ds.Ship{}).Where("ship_id = ?", shipID).Update("photo_url", photo.FileName).Error
+	})
+}
+
+// DeleteShipPhoto - удалить фотографию из галереи и вернуть её (для удаления файла из хранилища).
+// Если удалена основная фотография, основной становится первая оставшаяся.
+func (r *Repository) DeleteShipPhoto(shipID, photoID int) (ds.ShipPhoto, error) {
+	var photo ds.ShipPhoto
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("ship_id = ? AND photo_id = ?", shipID, photoID).First(&photo).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&ds.ShipPhoto{}, photo.PhotoID).Error; err != nil {
+			return err
+		}
+
+		var ship ds.Ship
+		if err := tx.Where("ship_id = ?", shipID).First(&ship).Error; err != nil {
+			return err
+		}
+		if ship.PhotoURL != photo.FileName {
+			return nil
+		}
+
+		var next ds.ShipPhoto
+		err := tx.Where("ship_id = ?", shipID).Order("position, photo_id").First(&next).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		return tx.Model(&ds.Ship{}).Where("ship_id = ?", shipID).Update("photo_url", next.FileName).Error
+	})
+	if err != nil {
+		return ds.ShipPhoto{}, err
+	}
+	return photo, nil
+}
+
+// ensureLegacyShipPhoto - корабли, загруженные до появления галереи, имеют только PhotoURL;
+// добавляем её в галерею первой, чтобы она не потерялась при смене основной фотографии
+func ensureLegacyShipPhoto(tx *gorm.DB, ship ds.Ship) error {
+	if ship.PhotoURL == "" {
+		return nil
+	}
+
+	var count int64
+	if err := tx.Model(&ds.ShipPhoto{}).
+		Where("ship_id = ? AND file_name = ?", ship.ShipID, ship.PhotoURL).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	if err := tx.Model(&ds.ShipPhoto{}).Where("ship_id = ?", ship.ShipID).
+		Update("position", gorm.Expr("position + 1")).Error; err != nil {
+		return err
+	}
+	return tx.Create(&ds.ShipPhoto{
+		ShipID:    ship.ShipID,
+		FileName:  ship.PhotoURL,
+		Position:  0,
+		CreatedAt: time.Now(),
+	}).Error
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"loading_time/internal/app/ds"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Полнотекстовый поиск по каталогу: ships.search_vector - генерируемый столбец tsvector
+// по названию (вес A) и описанию (вес B) в конфигурациях shipSearchConfigs, с GIN-индексом
+// (ShipSearchVectorSQL, см. cmd/loading_time/migrate и build/fill.sql). Запрос пользователя
+// разбирается websearch_to_tsquery в тех же конфигурациях, совпадение по любой из них.
+
+// ShipSortRank - сортировка по релевантности, доступна только при ShipFilter.Query
+const ShipSortRank = "rank"
+
+// ErrRankWithoutQuery - сортировка по релевантности без поискового запроса
+var ErrRankWithoutQuery = errors.New("sorting by rank requires a search query")
+
+// Конфигурации текстового поиска: одни и те же для индекса, запроса и подсветки
+const (
+	shipSearchConfig      = "russian" // основная, по ней ts_headline разбирает текст для подсветки
+	shipSearchExtraConfig = "english"
+)
+
+var shipSearchConfigs = [...]string{shipSearchConfig, shipSearchExtraConfig}
+
+// ShipSearchVectorSQL - выражение генерируемого столбца ships.search_vector
+func ShipSearchVectorSQL() string {
+	var parts []string
+	for _, column := range []struct{ name, weight string }{{"name", "A"}, {"description", "B"}} {
+		for _, config := range shipSearchConfigs {
+			parts = append(parts, fmt.Sprintf("setweight(to_tsvector('%s', coalesce(%s, '')), '%s')", config, column.name, column.weight))
+		}
+	}
+	return strings.Join(parts, " ||\n")
+}
+
+const (
+	shipSearchRank = "ts_rank(ships.search_vector, search.q)"
+
+	// маркеры подсветки в ts_headline: управляющие символы не встречаются в тексте,
+	// поэтому после экранирования HTML их можно безопасно заменить на <mark>
+	highlightStart = "\x01"
+	highlightStop  = "\x02"
+
+	nameHeadlineOptions        = "StartSel=\x01, StopSel=\x02, HighlightAll=true"
+	descriptionHeadlineOptions = "StartSel=\x01, StopSel=\x02, MaxWords=35, MinWords=15, ShortWord=2, MaxFragments=2, FragmentDelimiter=\" … \""
+)
+
+// withShipSearch - фильтр по поисковому запросу; запрос доступен в выражениях как search.q
+func withShipSearch(query *gorm.DB, text string) *gorm.DB {
+	tsqueries := make([]string, len(shipSearchConfigs))
+	args := make([]interface{}, 0, 2*len(shipSearchConfigs))
+	for i, config := range shipSearchConfigs {
+		tsqueries[i] = "websearch_to_tsquery(?::regconfig, ?)"
+		args = append(args, config, text)
+	}
+	return query.
+		Joins("CROSS JOIN (SELECT "+strings.Join(tsqueries, " || ")+" AS q) AS search", args...).
+		Where("ships.search_vector @@ search.q")
+}
+
+// shipSearchRow - корабль вместе с релевантностью и фрагментами с подсветкой
+type shipSearchRow struct {
+	ds.Ship
+	SearchRank          float64
+	NameHeadline        string
+	DescriptionHeadline string
+}
+
+// findShipsWithMatches - выполнить запрос с withShipSearch и заполнить Ship.Match
+func findShipsWithMatches(query *gorm.DB) ([]ds.Ship, error) {
+	var rows []shipSearchRow
+	err := query.
+		Select("ships.*, "+shipSearchRank+" AS search_rank, "+
+			"ts_headline(?::regconfig, ships.name, search.q, ?) AS name_headline, "+
+			"ts_headline(?::regconfig, COALESCE(ships.description, ''), search.q, ?) AS description_headline",
+			shipSearchConfig, nameHeadlineOptions, shipSearchConfig, descriptionHeadlineOptions).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	ships := make([]ds.Ship, len(rows))
+	for i, row := range rows {
+		ships[i] = row.Ship
+		ships[i].Match = &ds.ShipMatch{
+			Rank:        row.SearchRank,
+			Name:        highlightHTML(row.NameHeadline),
+			Description: highlightHTML(row.DescriptionHeadline),
+		}
+	}
+	return ships, nil
+}
+
+// highlightHTML - экранированный фрагмент, в котором совпадения обёрнуты в <mark>
+func highlightHTML(headline string) string {
+	escaped := html.EscapeString(headline)
+	return strings.NewReplacer(highlightStart, "<mark>", highlightStop, "</mark>").Replace(escaped)
+}
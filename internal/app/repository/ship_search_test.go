@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestShipSearchVectorMatchesFillSQL(t *testing.T) {
+	fill, err := os.ReadFile("../../../build/fill.sql")
+	if err != nil {
+		t.Fatalf("read fill.sql: %v", err)
+	}
+	normalize := func(s string) string { return strings.Join(strings.Fields(s), " ") }
+	if !strings.Contains(normalize(string(fill)), normalize(ShipSearchVectorSQL())) {
+		t.Errorf("build/fill.sql does not define search_vector as ShipSearchVectorSQL():\n%s", ShipSearchVectorSQL())
+	}
+}
+
+func TestShipSearchUsesSameConfigs(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	// запрос разбирается в конфигурациях индекса, подсветка - в основной
+	mock.ExpectQuery(`ts_headline\(\$1::regconfig, ships.name.*ts_headline\(\$3::regconfig.*websearch_to_tsquery\(\$5::regconfig, \$6\) \|\| websearch_to_tsquery\(\$7::regconfig, \$8\)`).
+		WithArgs(shipSearchConfig, nameHeadlineOptions, shipSearchConfig, descriptionHeadlineOptions,
+			shipSearchConfig, "crane", shipSearchExtraConfig, "crane").
+		WillReturnRows(sqlmock.NewRows([]string{"ship_id"}))
+
+	if _, err := findShipsWithMatches(withShipSearch(repo.db.Table("ships"), "crane")); err != nil {
+		t.Fatalf("findShipsWithMatches: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
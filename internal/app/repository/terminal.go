@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"errors"
+	"loading_time/internal/app/ds"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrBerthNotFound - заявка ссылается на несуществующий причал
+	ErrBerthNotFound = errors.New("berth not found")
+	// ErrTerminalHasBerths - удалить можно только терминал без причалов
+	ErrTerminalHasBerths = errors.New("terminal still has berths")
+	// ErrBerthInUse - на причал ссылаются заявки
+	ErrBerthInUse = errors.New("berth is referenced by requests")
+)
+
+// GetTerminals - терминалы с причалами, по названию
+func (r *Repository) GetTerminals() ([]ds.Terminal, error) {
+	var terminals []ds.Terminal
+	err := r.db.Preload("Berths", orderBerths).Order("name, terminal_id").Find(&terminals).Error
+	if err != nil {
+		return nil, err
+	}
+	return terminals, nil
+}
+
+// GetTerminal - терминал с причалами
+func (r *Repository) GetTerminal(terminalID int) (ds.Terminal, error) {
+	var terminal ds.Terminal
+	err := r.db.Preload("Berths", orderBerths).Where("terminal_id = ?", terminalID).First(&terminal).Error
+	if err != nil {
+		return ds.Terminal{}, err
+	}
+	return terminal, nil
+}
+
+func orderBerths(db *gorm.DB) *gorm.DB {
+	return db.Order("name, berth_id")
+}
+
+// CreateTerminal - новый терминал (без причалов)
+func (r *Repository) CreateTerminal(terminal *ds.Terminal) error {
+	return r.db.Omit("Berths").Create(terminal).Error
+}
+
+// UpdateTerminal - название и порт терминала
+func (r *Repository) UpdateTerminal(terminalID int, terminal ds.Terminal) error {
+	result := r.db.Model(&ds.Terminal{}).Where("terminal_id = ?", terminalID).
+		Select("name", "port").Updates(&terminal)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteTerminal - удалить терминал; причалы нужно удалить заранее
+func (r *Repository) DeleteTerminal(terminalID int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var berths int64
+		if err := tx.Model(&ds.Berth{}).Where("terminal_id = ?", terminalID).Count(&berths).Error; err != nil {
+			return err
+		}
+		if berths > 0 {
+			return ErrTerminalHasBerths
+		}
+
+		result := tx.Delete(&ds.Terminal{}, terminalID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// GetBerth - причал по ID
+func (r *Repository) GetBerth(berthID int) (ds.Berth, error) {
+	var berth ds.Berth
+	err := r.db.Where("berth_id = ?", berthID).First(&berth).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ds.Berth{}, ErrBerthNotFound
+	}
+	if err != nil {
+		return ds.Berth{}, err
+	}
+	return berth, nil
+}
+
+// CreateBerth - новый причал терминала berth.TerminalID
+func (r *Repository) CreateBerth(berth *ds.Berth) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("terminal_id = ?", berth.TerminalID).First(&ds.Terminal{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(berth).Error
+	})
+}
+
+// UpdateBerth - название, ограничения и краны причала (нулевые значения тоже сохраняются)
+func (r *Repository) UpdateBerth(berthID int, berth ds.Berth) error {
+	result := r.db.Model(&ds.Berth{}).Where("berth_id = ?", berthID).
+		Select("name", "max_length", "max_beam", "max_depth", "cranes").Updates(&berth)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrBerthNotFound
+	}
+	return nil
+}
+
+// DeleteBerth - удалить причал, если на него не ссылается ни одна заявка
+func (r *Repository) DeleteBerth(berthID int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var requests int64
+		if err := tx.Model(&ds.RequestShip{}).Where("berth_id = ?", berthID).Count(&requests).Error; err != nil {
+			return err
+		}
+		if requests > 0 {
+			return ErrBerthInUse
+		}
+
+		result := tx.Delete(&ds.Berth{}, berthID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrBerthNotFound
+		}
+		return nil
+	})
+}
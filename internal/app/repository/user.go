@@ -1,25 +1,32 @@
 package repository
 
 import (
-	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"loading_time/internal/app/ds"
-	"strconv"
+	"loading_time/internal/app/policy"
 	"strings"
-	"time"
 
-	"github.com/golang-jwt/jwt/v4"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 // NOTE: этот файл реализует: CreateUser, GetUserByLogin, RegisterUser,
-// Authenticate, LoginUser, SaveJWTToken, SaveSession и работу с Redis.
-// Он ориентирован на структуру Repository, у которой должны быть поля:
-// db *gorm.DB, redisClient *redis.Client, jwtKey string
-// (см. инструкцию внизу, если нужно инициализировать redisClient/jwtKey).
+// Authenticate и работу с гостями. Токены выпускает auth.TokenService.
+
+// guestLoginPrefix - логины с этим префиксом зарезервированы за гостями
+const guestLoginPrefix = "guest:"
+
+// ErrReservedLogin - логин зарезервирован за служебными пользователями (гостями)
+var ErrReservedLogin = errors.New("login is reserved")
+
+// IsReservedLogin - занимать такой логин при регистрации и в профиле нельзя
+func IsReservedLogin(login string) bool {
+	return strings.HasPrefix(login, guestLoginPrefix)
+}
 
 // GetUserByLogin returns user by login
 func (r *Repository) GetUserByLogin(login string) (*ds.User, error) {
@@ -33,17 +40,19 @@ func (r *Repository) GetUserByLogin(login string) (*ds.User, error) {
 
 // CreateUser hashes password and saves new user
 func (r *Repository) CreateUser(user *ds.User) error {
+	if user.Role != policy.RoleGuest && IsReservedLogin(user.Login) {
+		return ErrReservedLogin
+	}
+
 	// Проверка: не пришёл ли уже хеш вместо пароля
 	if len(user.Password) > 0 && strings.HasPrefix(user.Password, "$2a$") {
 		logrus.Infof("CreateUser: пароль уже хеширован, не трогаем login=%s", user.Login)
 	} else {
-		logrus.Infof("CreateUser: raw password='%s' len=%d", user.Password, len(user.Password))
 		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
 		if err != nil {
 			return err
 		}
 		user.Password = string(hashedPassword)
-		logrus.Infof("CreateUser: generated hash for login=%s hash=%s len=%d", user.Login, user.Password, len(user.Password))
 	}
 
 	return r.db.Create(user).Error
@@ -56,9 +65,8 @@ func (r *Repository) RegisterUser(user ds.User) (ds.User, error) {
 	if err == nil && exist != nil {
 		return ds.User{}, fmt.Errorf("user already exists")
 	}
-	if user.Role == "" {
-		user.Role = "creator"
-	}
+	// роль выбирает не клиент: регистрация всегда создаёт автора заявок
+	user.Role = policy.RoleCreator
 	if err := r.CreateUser(&user); err != nil {
 		return ds.User{}, err
 	}
@@ -73,15 +81,12 @@ func (r *Repository) RegisterUser(user ds.User) (ds.User, error) {
 
 // Authenticate: возвращает пользователя, если логин+пароль верны
 func (r *Repository) Authenticate(login, password string) (*ds.User, error) {
-	fmt.Printf("DEBUG: Authenticate called for login=%s\n", login)
-
 	user, err := r.GetUserByLogin(login)
 	if err != nil {
 		return nil, fmt.Errorf("user not found: %w", err)
 	}
 	// сравниваем хэш
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		fmt.Printf("DEBUG: bcrypt compare failed for login=%s err=%v\n", login, err)
 		return nil, fmt.Errorf("invalid password")
 	}
 	// не отдаём пароль дальше
@@ -89,109 +94,65 @@ func (r *Repository) Authenticate(login, password string) (*ds.User, error) {
 	return user, nil
 }
 
-// LoginUser: полноценный flow — проверка, генерация JWT, сохранение токена и сессии
-func (r *Repository) LoginUser(login, password string) (jwtToken string, sessionID string, err error) {
-	fmt.Printf("DEBUG: LoginUser called. db=%v redis=%v jwtKeyLen=%d\n", r.db, r.redisClient, len(r.jwtKey))
-
-	// базовые проверки инициализации
-	if r.db == nil {
-		return "", "", fmt.Errorf("db is nil")
-	}
-	if r.redisClient == nil {
-		return "", "", fmt.Errorf("redis client is nil")
-	}
-	if r.jwtKey == "" {
-		return "", "", fmt.Errorf("jwt key is empty")
-	}
-
-	// проверка учётных данных
-	user, err := r.GetUserByLogin(login)
-	if err != nil {
-		return "", "", fmt.Errorf("user not found: %w", err)
-	}
-
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		// ПЕЧАТАЕМ ХЭШ и введённый пароль (только временно для отладки!)
-		fmt.Printf("DEBUG: password mismatch. dbHash=%s provided=%s err=%v\n", user.Password, password, err)
-		return "", "", fmt.Errorf("неверный пароль")
-	}
-
-	// claims
-	claims := jwt.MapClaims{
-		"user_id": user.UserID,
-		"role":    user.Role,
-		"exp":     time.Now().Add(24 * time.Hour).Unix(),
-	}
-	tokenObj := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenStr, err := tokenObj.SignedString([]byte(r.jwtKey))
-	if err != nil {
-		return "", "", fmt.Errorf("jwt sign error: %w", err)
-	}
-
-	// Сохранить JWT в Redis: ключ "jwt:<userID>" -> token
-	if err := r.SaveJWTToken(user.UserID, tokenStr); err != nil {
-		return "", "", fmt.Errorf("save jwt token error: %w", err)
-	}
-
-	// создать session id
-	sid := make([]byte, 16)
-	if _, err := rand.Read(sid); err != nil {
-		return "", "", fmt.Errorf("rand read session id error: %w", err)
-	}
-	sessionID = hex.EncodeToString(sid)
+//__________________________________________________________________________________________
 
-	// сохранить сессию: key "sess:<sessionID>" -> fields user_id, role
-	if err := r.SaveSession(sessionID, user.UserID, user.Role, 24*time.Hour); err != nil {
-		return "", "", fmt.Errorf("save session error: %w", err)
+// GetUserByID — получить пользователя по ID
+func (r *Repository) GetUserByID(userID int) (*ds.User, error) {
+	user := &ds.User{}
+	if err := r.db.First(user, userID).Error; err != nil {
+		return nil, err
 	}
+	return user, nil
+}
 
-	fmt.Printf("DEBUG: LoginUser success login=%s userID=%d session=%s\n", login, user.UserID, sessionID)
-	return tokenStr, sessionID, nil
+// guestLogin — логин служебного пользователя, которому принадлежит черновик гостя
+func guestLogin(guestToken string) string {
+	return guestLoginPrefix + guestToken
 }
 
-// SaveJWTToken stores token in redis with TTL
-func (r *Repository) SaveJWTToken(userID int, token string) error {
-	key := "jwt:" + strconv.Itoa(userID)
-	err := r.redisClient.Set(context.Background(), key, token, 24*time.Hour).Err()
+// getGuestUser — гость по токену из cookie; пользователи с другими ролями не находятся,
+// даже если их логин совпал с гостевым
+func (r *Repository) getGuestUser(guestToken string) (*ds.User, error) {
+	user := &ds.User{}
+	err := r.db.Where("login = ? AND role = ?", guestLogin(guestToken), policy.RoleGuest).First(user).Error
 	if err != nil {
-		fmt.Printf("DEBUG: SaveJWTToken error: %v\n", err)
-		return err
+		return nil, err
 	}
-	fmt.Printf("DEBUG: SaveJWTToken saved key=%s\n", key)
-	return nil
+	return user, nil
 }
 
-// SaveSession stores session map in redis
-func (r *Repository) SaveSession(sessionID string, userID int, role string, ttl time.Duration) error {
-	key := "sess:" + sessionID
-	data := map[string]interface{}{
-		"user_id": strconv.Itoa(userID),
-		"role":    role,
-	}
-	if err := r.redisClient.HSet(context.Background(), key, data).Err(); err != nil {
-		fmt.Printf("DEBUG: SaveSession HSet error: %v\n", err)
-		return err
+// GetOrCreateGuestUser — найти или создать гостя по токену из cookie.
+// Заявка обязана ссылаться на users, поэтому черновик гостя хранится за отдельной записью с ролью policy.RoleGuest.
+func (r *Repository) GetOrCreateGuestUser(guestToken string) (ds.User, error) {
+	user, err := r.getGuestUser(guestToken)
+	if err == nil {
+		return *user, nil
 	}
-	if err := r.redisClient.Expire(context.Background(), key, ttl).Err(); err != nil {
-		fmt.Printf("DEBUG: SaveSession Expire error: %v\n", err)
-		return err
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return ds.User{}, err
 	}
-	fmt.Printf("DEBUG: SaveSession saved key=%s ttl=%v\n", key, ttl)
-	return nil
-}
 
-//__________________________________________________________________________________________
+	pass := make([]byte, 16)
+	if _, err := rand.Read(pass); err != nil {
+		return ds.User{}, fmt.Errorf("rand read guest password error: %w", err)
+	}
 
-// GetUserByID — получить пользователя по ID
-func (r *Repository) GetUserByID(userID int) (*ds.User, error) {
-	user := &ds.User{}
-	if err := r.db.First(user, userID).Error; err != nil {
-		return nil, err
+	guest := ds.User{
+		FIO:      "Гость",
+		Login:    guestLogin(guestToken),
+		Password: hex.EncodeToString(pass),
+		Role:     policy.RoleGuest,
 	}
-	return user, nil
+	if err := r.CreateUser(&guest); err != nil {
+		return ds.User{}, err
+	}
+	return guest, nil
 }
 
 // UpdateUser — обновить данные пользователя
 func (r *Repository) UpdateUser(user ds.User) error {
+	if IsReservedLogin(user.Login) {
+		return ErrReservedLogin
+	}
 	return r.db.Model(&ds.User{}).Where("user_id = ?", user.UserID).Updates(user).Error
 }
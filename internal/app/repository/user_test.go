@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+
+	"loading_time/internal/app/ds"
+	"loading_time/internal/app/policy"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestReservedLoginRejected(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	if err := repo.CreateUser(&ds.User{Login: "guest:abc", Password: "secret", Role: policy.RoleCreator}); !errors.Is(err, ErrReservedLogin) {
+		t.Errorf("CreateUser(guest:abc): err = %v, want ErrReservedLogin", err)
+	}
+	// RegisterUser сначала проверяет, не занят ли логин
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE login = \$1`).
+		WithArgs("guest:abc", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+	if _, err := repo.RegisterUser(ds.User{Login: "guest:abc", Password: "secret"}); !errors.Is(err, ErrReservedLogin) {
+		t.Errorf("RegisterUser(guest:abc): err = %v, want ErrReservedLogin", err)
+	}
+	if err := repo.UpdateUser(ds.User{UserID: 1, Login: "guest:abc"}); !errors.Is(err, ErrReservedLogin) {
+		t.Errorf("UpdateUser(guest:abc): err = %v, want ErrReservedLogin", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMergeGuestDraftIgnoresNonGuestLogin(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	// пользователь с логином guest:abc, но не гость, не находится и не удаляется
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE login = \$1 AND role = \$2`).
+		WithArgs("guest:abc", policy.RoleGuest, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+
+	if err := repo.MergeGuestDraft("abc", 7); err != nil {
+		t.Fatalf("MergeGuestDraft: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,179 @@
+package session
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MemoryStore - сессии в памяти процесса: для разработки и тестов без Redis.
+// Данные не переживают перезапуск и не разделяются между экземплярами сервера.
+// Истёкшие записи вычищаются при каждой записи, так что память не растёт без предела.
+type MemoryStore struct {
+	mu       sync.Mutex
+	sessions map[string]memorySession
+	refresh  map[string]memoryRefreshToken // по хэшу токена
+}
+
+type memorySession struct {
+	Session
+	expiresAt time.Time
+}
+
+type memoryRefreshToken struct {
+	userID    int
+	family    string
+	used      bool
+	expiresAt time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		sessions: map[string]memorySession{},
+		refresh:  map[string]memoryRefreshToken{},
+	}
+}
+
+func (s *MemoryStore) SetSession(_ context.Context, sess Session, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.purgeExpired()
+	if sess.Family != "" && !s.familyActive(sess.Family) {
+		return ErrRefreshTokenInvalid
+	}
+	s.sessions[sess.ID] = memorySession{Session: sess, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+func (s *MemoryStore) SessionActive(_ context.Context, sessionID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[sessionID]
+	if ok && !time.Now().Before(sess.expiresAt) {
+		delete(s.sessions, sessionID)
+		return false, nil
+	}
+	return ok, nil
+}
+
+func (s *MemoryStore) DeleteSession(_ context.Context, sessionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, sessionID)
+	return nil
+}
+
+func (s *MemoryStore) DeleteUserSessions(_ context.Context, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, sess := range s.sessions {
+		if sess.UserID == userID {
+			delete(s.sessions, id)
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) CreateRefreshToken(_ context.Context, userID int, ttl time.Duration) (RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.issueRefreshToken(userID, uuid.NewString(), ttl)
+}
+
+func (s *MemoryStore) RotateRefreshToken(_ context.Context, token string, ttl time.Duration) (RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hash := hashRefreshToken(token)
+	stored, ok := s.refresh[hash]
+	if !ok || !time.Now().Before(stored.expiresAt) {
+		delete(s.refresh, hash)
+		return RefreshToken{}, ErrRefreshTokenInvalid
+	}
+	if stored.used {
+		s.revokeRefreshFamily(stored.family)
+		return RefreshToken{}, ErrRefreshTokenReused
+	}
+	stored.used = true
+	s.refresh[hash] = stored
+
+	return s.issueRefreshToken(stored.userID, stored.family, ttl)
+}
+
+func (s *MemoryStore) RevokeRefreshToken(_ context.Context, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if stored, ok := s.refresh[hashRefreshToken(token)]; ok {
+		s.revokeRefreshFamily(stored.family)
+	}
+	return nil
+}
+
+func (s *MemoryStore) RevokeUserRefreshTokens(_ context.Context, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, stored := range s.refresh {
+		if stored.userID == userID {
+			s.revokeRefreshFamily(stored.family)
+		}
+	}
+	return nil
+}
+
+// revokeRefreshFamily - удалить все токены семейства и выданные по ним сессии (вызывается под s.mu)
+func (s *MemoryStore) revokeRefreshFamily(family string) {
+	for hash, stored := range s.refresh {
+		if stored.family == family {
+			delete(s.refresh, hash)
+		}
+	}
+	for id, sess := range s.sessions {
+		if sess.Family == family {
+			delete(s.sessions, id)
+		}
+	}
+}
+
+// purgeExpired - удалить истёкшие сессии и refresh-токены (вызывается под s.mu)
+func (s *MemoryStore) purgeExpired() {
+	now := time.Now()
+	for id, sess := range s.sessions {
+		if !now.Before(sess.expiresAt) {
+			delete(s.sessions, id)
+		}
+	}
+	for hash, stored := range s.refresh {
+		if !now.Before(stored.expiresAt) {
+			delete(s.refresh, hash)
+		}
+	}
+}
+
+// familyActive - есть ли у семейства неистёкшие токены (вызывается под s.mu)
+func (s *MemoryStore) familyActive(family string) bool {
+	now := time.Now()
+	for _, stored := range s.refresh {
+		if stored.family == family && now.Before(stored.expiresAt) {
+			return true
+		}
+	}
+	return false
+}
+
+// issueRefreshToken - новый токен семейства family (вызывается под s.mu)
+func (s *MemoryStore) issueRefreshToken(userID int, family string, ttl time.Duration) (RefreshToken, error) {
+	s.purgeExpired()
+	token, err := newRefreshToken()
+	if err != nil {
+		return RefreshToken{}, err
+	}
+	expiresAt := time.Now().Add(ttl)
+	s.refresh[hashRefreshToken(token)] = memoryRefreshToken{
+		userID:    userID,
+		family:    family,
+		expiresAt: expiresAt,
+	}
+	return RefreshToken{Token: token, UserID: userID, Family: family, ExpiresAt: expiresAt}, nil
+}
@@ -0,0 +1,42 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	t.Run("sessions", func(t *testing.T) { testStoreSessions(t, NewMemoryStore()) })
+	t.Run("rotate", func(t *testing.T) { testStoreRotate(t, NewMemoryStore()) })
+	t.Run("reuse revokes family", func(t *testing.T) { testStoreReuseRevokesFamily(t, NewMemoryStore()) })
+	t.Run("revoke user", func(t *testing.T) { testStoreRevokeUser(t, NewMemoryStore()) })
+}
+
+func TestMemoryStoreExpiry(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	if err := store.SetSession(ctx, Session{ID: "short", UserID: 1}, time.Millisecond); err != nil {
+		t.Fatalf("SetSession: %v", err)
+	}
+	refresh, err := store.CreateRefreshToken(ctx, 1, time.Millisecond)
+	if err != nil {
+		t.Fatalf("CreateRefreshToken: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	if _, err := store.RotateRefreshToken(ctx, refresh.Token, time.Hour); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("rotate expired token: err = %v, want ErrRefreshTokenInvalid", err)
+	}
+
+	// запись вычищает истёкшие сессии, даже если их никто не проверял
+	if err := store.SetSession(ctx, Session{ID: "long", UserID: 1}, time.Hour); err != nil {
+		t.Fatalf("SetSession: %v", err)
+	}
+	if _, ok := store.sessions["short"]; ok {
+		t.Error("expired session was not purged on write")
+	}
+	assertActive(t, store, "long", true)
+}
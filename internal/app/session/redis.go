@@ -0,0 +1,363 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore - сессии в Redis:
+//
+//	session:<id>              - hash {user_id, role, family}, TTL - срок жизни JWT
+//	user_sessions:<user_id>   - set id сессий пользователя
+//	refresh:<hash>            - hash {user_id, family, used}, TTL - срок жизни refresh-токена
+//	refresh_family:<id>       - set хэшей токенов семейства
+//	family_sessions:<id>      - set id сессий, выданных по токенам семейства
+//	user_refresh:<user_id>    - set семейств пользователя
+//
+// Обмен и отзыв refresh-токенов выполняются Lua-скриптами, атомарно относительно друг друга;
+// все ключи, которые читает или меняет скрипт, передаются ему в KEYS.
+type RedisStore struct {
+	client *redis.Client
+}
+
+// NewRedisStore - подключение к Redis по адресу "host:port"; пароль может быть ""
+func NewRedisStore(addr, password string) (*RedisStore, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: password,
+		DB:       0,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		return nil, fmt.Errorf("redis ping failed: %w", err)
+	}
+	return &RedisStore{client: client}, nil
+}
+
+func sessionKey(sessionID string) string {
+	return "session:" + sessionID
+}
+
+func userSessionsKey(userID int) string {
+	return "user_sessions:" + strconv.Itoa(userID)
+}
+
+func refreshTokenKey(hash string) string {
+	return "refresh:" + hash
+}
+
+func refreshFamilyKey(family string) string {
+	return "refresh_family:" + family
+}
+
+func familySessionsKey(family string) string {
+	return "family_sessions:" + family
+}
+
+func userRefreshKey(userID int) string {
+	return "user_refresh:" + strconv.Itoa(userID)
+}
+
+// maxScriptAttempts - сколько раз повторить скрипт, если состав семейства изменился
+// между чтением его ключей и выполнением скрипта
+const maxScriptAttempts = 5
+
+// errFamilyChanged - состав семейства менялся на каждой попытке
+var errFamilyChanged = errors.New("refresh token family changed concurrently")
+
+// familyLua - общая часть скриптов. Все затрагиваемые ключи передаются в KEYS: начиная с
+// KEYS[first] идут set токенов семейства, set его сессий и ключи их элементов (см. familyKeys).
+// Имена элементов строятся только для сверки с KEYS, сами ключи скрипт берёт из KEYS.
+const familyLua = `
+local function family_keys_match(first)
+	local passed = {}
+	for i = first + 2, #KEYS do
+		passed[KEYS[i]] = true
+	end
+	for _, hash in ipairs(redis.call('SMEMBERS', KEYS[first])) do
+		if not passed['refresh:' .. hash] then
+			return false
+		end
+	end
+	for _, id in ipairs(redis.call('SMEMBERS', KEYS[first + 1])) do
+		if not passed['session:' .. id] then
+			return false
+		end
+	end
+	return true
+end
+
+local function revoke_family(first)
+	for i = first, #KEYS do
+		redis.call('DEL', KEYS[i])
+	end
+end
+`
+
+// revokeFamilyScript - KEYS - ключи семейства (familyKeys). Возвращает 1 или 0, если
+// состав семейства изменился и ключи нужно прочитать заново.
+var revokeFamilyScript = redis.NewScript(familyLua + `
+if not family_keys_match(1) then
+	return 0
+end
+revoke_family(1)
+return 1
+`)
+
+// rotateScript - KEYS: предъявленный токен, новый токен, user_refresh:<user_id> и ключи
+// семейства (familyKeys; элементы - только если токен уже использован). ARGV: хэш нового
+// токена, TTL в мс, время обмена, ожидаемые user_id и семейство.
+// Возвращает {"ok", user_id, family}, {"reused"}, {"invalid"} или {"retry"}, если ключи
+// прочитаны до изменения токена или семейства.
+// Токен, удалённый отзывом до обмена, не находится: новый токен в отозванное семейство не попадёт.
+var rotateScript = redis.NewScript(familyLua + `
+local stored = redis.call('HMGET', KEYS[1], 'user_id', 'family', 'used')
+local user_id, family, used = stored[1], stored[2], stored[3]
+if not user_id or not family then
+	return {'invalid'}
+end
+if user_id ~= ARGV[4] or family ~= ARGV[5] then
+	return {'retry'}
+end
+if used then
+	if not family_keys_match(4) then
+		return {'retry'}
+	end
+	revoke_family(4)
+	return {'reused'}
+end
+
+redis.call('HSET', KEYS[1], 'used', ARGV[3])
+redis.call('HSET', KEYS[2], 'user_id', user_id, 'family', family)
+redis.call('PEXPIRE', KEYS[2], ARGV[2])
+-- семейство живёт, пока жив его последний токен
+redis.call('SADD', KEYS[4], ARGV[1])
+redis.call('PEXPIRE', KEYS[4], ARGV[2])
+redis.call('SADD', KEYS[3], family)
+redis.call('PEXPIRE', KEYS[3], ARGV[2])
+return {'ok', user_id, family}
+`)
+
+// setSessionScript - KEYS: session:<id>, user_sessions:<user_id> и для сессии семейства -
+// refresh_family:<id>, family_sessions:<id>. ARGV: id, user_id, role, family, TTL в мс.
+// Сессия семейства создаётся, только пока семейство не отозвано; возвращает 0, если отозвано.
+var setSessionScript = redis.NewScript(`
+if #KEYS == 4 then
+	if redis.call('EXISTS', KEYS[3]) == 0 then
+		return 0
+	end
+	redis.call('SADD', KEYS[4], ARGV[1])
+	redis.call('PEXPIRE', KEYS[4], math.max(tonumber(ARGV[5]), redis.call('PTTL', KEYS[3])))
+end
+redis.call('HSET', KEYS[1], 'user_id', ARGV[2], 'role', ARGV[3], 'family', ARGV[4])
+redis.call('PEXPIRE', KEYS[1], ARGV[5])
+-- set живёт не меньше самой долгой сессии пользователя
+redis.call('SADD', KEYS[2], ARGV[1])
+redis.call('PEXPIRE', KEYS[2], ARGV[5])
+return 1
+`)
+
+// familyKeys - ключи семейства для скриптов: set токенов, set сессий и, если withMembers,
+// ключи всех токенов и сессий семейства
+func (s *RedisStore) familyKeys(ctx context.Context, family string, withMembers bool) ([]string, error) {
+	keys := []string{refreshFamilyKey(family), familySessionsKey(family)}
+	if !withMembers {
+		return keys, nil
+	}
+
+	hashes, err := s.client.SMembers(ctx, refreshFamilyKey(family)).Result()
+	if err != nil {
+		return nil, err
+	}
+	sessionIDs, err := s.client.SMembers(ctx, familySessionsKey(family)).Result()
+	if err != nil {
+		return nil, err
+	}
+	for _, hash := range hashes {
+		keys = append(keys, refreshTokenKey(hash))
+	}
+	for _, id := range sessionIDs {
+		keys = append(keys, sessionKey(id))
+	}
+	return keys, nil
+}
+
+// revokeFamily - удалить токены и сессии семейства
+func (s *RedisStore) revokeFamily(ctx context.Context, family string) error {
+	for attempt := 0; attempt < maxScriptAttempts; attempt++ {
+		keys, err := s.familyKeys(ctx, family, true)
+		if err != nil {
+			return err
+		}
+		done, err := revokeFamilyScript.Run(ctx, s.client, keys).Int()
+		if err != nil {
+			return err
+		}
+		if done == 1 {
+			return nil
+		}
+	}
+	return errFamilyChanged
+}
+
+func (s *RedisStore) SetSession(ctx context.Context, sess Session, ttl time.Duration) error {
+	keys := []string{sessionKey(sess.ID), userSessionsKey(sess.UserID)}
+	if sess.Family != "" {
+		keys = append(keys, refreshFamilyKey(sess.Family), familySessionsKey(sess.Family))
+	}
+	ok, err := setSessionScript.Run(ctx, s.client, keys,
+		sess.ID, sess.UserID, sess.Role, sess.Family, ttl.Milliseconds(),
+	).Int()
+	if err != nil {
+		return err
+	}
+	if ok == 0 {
+		return ErrRefreshTokenInvalid
+	}
+	return nil
+}
+
+func (s *RedisStore) SessionActive(ctx context.Context, sessionID string) (bool, error) {
+	n, err := s.client.Exists(ctx, sessionKey(sessionID)).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (s *RedisStore) DeleteSession(ctx context.Context, sessionID string) error {
+	key := sessionKey(sessionID)
+	userID, err := s.client.HGet(ctx, key, "user_id").Int()
+	if err == redis.Nil {
+		return nil // уже истекла или отозвана
+	}
+	if err != nil {
+		return err
+	}
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, key)
+		pipe.SRem(ctx, userSessionsKey(userID), sessionID)
+		return nil
+	})
+	return err
+}
+
+func (s *RedisStore) DeleteUserSessions(ctx context.Context, userID int) error {
+	sessionIDs, err := s.client.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(sessionIDs)+1)
+	for _, id := range sessionIDs {
+		keys = append(keys, sessionKey(id))
+	}
+	keys = append(keys, userSessionsKey(userID))
+	return s.client.Del(ctx, keys...).Err()
+}
+
+func (s *RedisStore) CreateRefreshToken(ctx context.Context, userID int, ttl time.Duration) (RefreshToken, error) {
+	token, err := newRefreshToken()
+	if err != nil {
+		return RefreshToken{}, err
+	}
+	hash := hashRefreshToken(token)
+	family := uuid.NewString()
+
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, refreshTokenKey(hash), map[string]interface{}{
+			"user_id": userID,
+			"family":  family,
+		})
+		pipe.Expire(ctx, refreshTokenKey(hash), ttl)
+		pipe.SAdd(ctx, refreshFamilyKey(family), hash)
+		pipe.Expire(ctx, refreshFamilyKey(family), ttl)
+		pipe.SAdd(ctx, userRefreshKey(userID), family)
+		pipe.Expire(ctx, userRefreshKey(userID), ttl)
+		return nil
+	})
+	if err != nil {
+		return RefreshToken{}, err
+	}
+	return RefreshToken{Token: token, UserID: userID, Family: family, ExpiresAt: time.Now().Add(ttl)}, nil
+}
+
+func (s *RedisStore) RotateRefreshToken(ctx context.Context, token string, ttl time.Duration) (RefreshToken, error) {
+	newToken, err := newRefreshToken()
+	if err != nil {
+		return RefreshToken{}, err
+	}
+	key := refreshTokenKey(hashRefreshToken(token))
+	newHash := hashRefreshToken(newToken)
+
+	for attempt := 0; attempt < maxScriptAttempts; attempt++ {
+		// ключи семейства и владельца нужны скрипту заранее
+		stored, err := s.client.HMGet(ctx, key, "user_id", "family", "used").Result()
+		if err != nil {
+			return RefreshToken{}, err
+		}
+		userID, _ := stored[0].(string)
+		family, _ := stored[1].(string)
+		if userID == "" || family == "" {
+			return RefreshToken{}, ErrRefreshTokenInvalid
+		}
+		uid, err := strconv.Atoi(userID)
+		if err != nil {
+			return RefreshToken{}, ErrRefreshTokenInvalid
+		}
+		familyKeys, err := s.familyKeys(ctx, family, stored[2] != nil)
+		if err != nil {
+			return RefreshToken{}, err
+		}
+
+		keys := append([]string{key, refreshTokenKey(newHash), userRefreshKey(uid)}, familyKeys...)
+		result, err := rotateScript.Run(ctx, s.client, keys,
+			newHash, ttl.Milliseconds(), time.Now().Unix(), userID, family,
+		).StringSlice()
+		if err != nil {
+			return RefreshToken{}, err
+		}
+
+		switch result[0] {
+		case "ok":
+			return RefreshToken{Token: newToken, UserID: uid, Family: family, ExpiresAt: time.Now().Add(ttl)}, nil
+		case "reused":
+			return RefreshToken{}, ErrRefreshTokenReused
+		case "invalid":
+			return RefreshToken{}, ErrRefreshTokenInvalid
+		}
+	}
+	return RefreshToken{}, errFamilyChanged
+}
+
+func (s *RedisStore) RevokeRefreshToken(ctx context.Context, token string) error {
+	family, err := s.client.HGet(ctx, refreshTokenKey(hashRefreshToken(token)), "family").Result()
+	if err == redis.Nil {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return s.revokeFamily(ctx, family)
+}
+
+func (s *RedisStore) RevokeUserRefreshTokens(ctx context.Context, userID int) error {
+	families, err := s.client.SMembers(ctx, userRefreshKey(userID)).Result()
+	if err != nil {
+		return err
+	}
+	for _, family := range families {
+		if err := s.revokeFamily(ctx, family); err != nil {
+			return err
+		}
+	}
+	return s.client.Del(ctx, userRefreshKey(userID)).Err()
+}
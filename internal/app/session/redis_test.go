@@ -0,0 +1,26 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// newTestRedisStore - RedisStore поверх miniredis (Lua-скрипты выполняются через gopher-lua)
+func newTestRedisStore(t *testing.T) *RedisStore {
+	t.Helper()
+	server := miniredis.RunT(t)
+	store, err := NewRedisStore(server.Addr(), "")
+	if err != nil {
+		t.Fatalf("NewRedisStore: %v", err)
+	}
+	t.Cleanup(func() { store.client.Close() })
+	return store
+}
+
+func TestRedisStore(t *testing.T) {
+	t.Run("sessions", func(t *testing.T) { testStoreSessions(t, newTestRedisStore(t)) })
+	t.Run("rotate", func(t *testing.T) { testStoreRotate(t, newTestRedisStore(t)) })
+	t.Run("reuse revokes family", func(t *testing.T) { testStoreReuseRevokesFamily(t, newTestRedisStore(t)) })
+	t.Run("revoke user", func(t *testing.T) { testStoreRevokeUser(t, newTestRedisStore(t)) })
+}
@@ -0,0 +1,98 @@
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"loading_time/internal/app/config"
+)
+
+const (
+	DriverRedis  = "redis"
+	DriverMemory = "memory"
+)
+
+// Store - хранилище сессий: по сессии на каждый выданный JWT (id - его jti, claims.ID)
+// и refresh-токены, которыми JWT обновляют. Токен действует, только пока жива его сессия.
+//
+// Refresh-токены объединены в семейства (одно семейство - один вход). При обмене токен
+// помечается использованным, а вместо него выдаётся новый того же семейства. Повторное
+// предъявление использованного токена означает, что его украли: всё семейство отзывается
+// вместе с сессиями JWT, выданных по его токенам.
+type Store interface {
+	// SetSession - сохранить сессию; для сессии семейства (Session.Family) -
+	// ErrRefreshTokenInvalid, если семейство уже отозвано
+	SetSession(ctx context.Context, s Session, ttl time.Duration) error
+	// SessionActive - не истекла ли и не отозвана ли сессия
+	SessionActive(ctx context.Context, sessionID string) (bool, error)
+	// DeleteSession - отозвать сессию; неизвестная сессия - не ошибка
+	DeleteSession(ctx context.Context, sessionID string) error
+	// DeleteUserSessions - отозвать все сессии пользователя (выход на всех устройствах)
+	DeleteUserSessions(ctx context.Context, userID int) error
+
+	// CreateRefreshToken - refresh-токен нового семейства (при входе пользователя)
+	CreateRefreshToken(ctx context.Context, userID int, ttl time.Duration) (RefreshToken, error)
+	// RotateRefreshToken - обменять refresh-токен на новый того же семейства.
+	// ErrRefreshTokenReused, если токен уже обменян (семейство и его сессии при этом отозваны)
+	RotateRefreshToken(ctx context.Context, token string, ttl time.Duration) (RefreshToken, error)
+	// RevokeRefreshToken - отозвать семейство токена и его сессии; неизвестный токен - не ошибка
+	RevokeRefreshToken(ctx context.Context, token string) error
+	// RevokeUserRefreshTokens - отозвать все refresh-токены пользователя
+	RevokeUserRefreshTokens(ctx context.Context, userID int) error
+}
+
+// Session - данные сессии
+type Session struct {
+	ID     string
+	UserID int
+	Role   string
+	Family string // семейство refresh-токенов, по которому выдан JWT
+}
+
+// RefreshToken - выданный refresh-токен
+type RefreshToken struct {
+	Token     string
+	UserID    int
+	Family    string
+	ExpiresAt time.Time
+}
+
+var (
+	// ErrRefreshTokenInvalid - токен не найден, истёк или его семейство отозвано
+	ErrRefreshTokenInvalid = errors.New("refresh token is invalid or expired")
+	// ErrRefreshTokenReused - токен уже был обменян; семейство отозвано
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected, all sessions of this login are revoked")
+)
+
+// FromConfig - хранилище, выбранное в config.Config; Redis - по RedisEndpoint/RedisPassword
+func FromConfig(cfg *config.Config) (Store, error) {
+	switch cfg.SessionStore.Driver {
+	case "", DriverRedis:
+		return NewRedisStore(cfg.RedisEndpoint, cfg.RedisPassword)
+	case DriverMemory:
+		return NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown session store driver %q", cfg.SessionStore.Driver)
+	}
+}
+
+// newRefreshToken - непрозрачный случайный refresh-токен
+func newRefreshToken() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// hashRefreshToken - в хранилище попадает только SHA-256 токена
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
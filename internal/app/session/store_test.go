@@ -0,0 +1,148 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Общие проверки контракта Store: выполняются для каждой реализации
+
+func testStoreSessions(t *testing.T, store Store) {
+	ctx := context.Background()
+
+	for _, sess := range []Session{
+		{ID: "a", UserID: 1, Role: "creator"},
+		{ID: "b", UserID: 1, Role: "creator"},
+		{ID: "c", UserID: 2, Role: "moderator"},
+	} {
+		if err := store.SetSession(ctx, sess, time.Hour); err != nil {
+			t.Fatalf("SetSession(%s): %v", sess.ID, err)
+		}
+	}
+	assertActive(t, store, "a", true)
+
+	if err := store.DeleteSession(ctx, "a"); err != nil {
+		t.Fatalf("DeleteSession: %v", err)
+	}
+	assertActive(t, store, "a", false)
+	assertActive(t, store, "b", true)
+
+	if err := store.DeleteUserSessions(ctx, 1); err != nil {
+		t.Fatalf("DeleteUserSessions: %v", err)
+	}
+	assertActive(t, store, "b", false)
+	assertActive(t, store, "c", true)
+}
+
+func testStoreRotate(t *testing.T, store Store) {
+	ctx := context.Background()
+
+	first, err := store.CreateRefreshToken(ctx, 7, time.Hour)
+	if err != nil {
+		t.Fatalf("CreateRefreshToken: %v", err)
+	}
+	second, err := store.RotateRefreshToken(ctx, first.Token, time.Hour)
+	if err != nil {
+		t.Fatalf("RotateRefreshToken: %v", err)
+	}
+	if second.UserID != 7 || second.Family != first.Family || second.Token == first.Token {
+		t.Errorf("rotated token = %+v, want user 7 of family %s with a new token", second, first.Family)
+	}
+	if _, err := store.RotateRefreshToken(ctx, "unknown", time.Hour); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("rotate unknown token: err = %v, want ErrRefreshTokenInvalid", err)
+	}
+}
+
+func testStoreReuseRevokesFamily(t *testing.T, store Store) {
+	ctx := context.Background()
+
+	stolen, err := store.CreateRefreshToken(ctx, 7, time.Hour)
+	if err != nil {
+		t.Fatalf("CreateRefreshToken: %v", err)
+	}
+	other, err := store.CreateRefreshToken(ctx, 7, time.Hour)
+	if err != nil {
+		t.Fatalf("CreateRefreshToken: %v", err)
+	}
+	current, err := store.RotateRefreshToken(ctx, stolen.Token, time.Hour)
+	if err != nil {
+		t.Fatalf("RotateRefreshToken: %v", err)
+	}
+	if err := store.SetSession(ctx, Session{ID: "family", UserID: 7, Family: current.Family}, time.Hour); err != nil {
+		t.Fatalf("SetSession: %v", err)
+	}
+	if err := store.SetSession(ctx, Session{ID: "other", UserID: 7, Family: other.Family}, time.Hour); err != nil {
+		t.Fatalf("SetSession: %v", err)
+	}
+
+	if _, err := store.RotateRefreshToken(ctx, stolen.Token, time.Hour); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("reuse: err = %v, want ErrRefreshTokenReused", err)
+	}
+	if _, err := store.RotateRefreshToken(ctx, current.Token, time.Hour); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("rotate after reuse: err = %v, want ErrRefreshTokenInvalid", err)
+	}
+	assertActive(t, store, "family", false)
+	assertActive(t, store, "other", true)
+
+	// новая сессия отозванного семейства не создаётся
+	err = store.SetSession(ctx, Session{ID: "late", UserID: 7, Family: current.Family}, time.Hour)
+	if !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("SetSession in revoked family: err = %v, want ErrRefreshTokenInvalid", err)
+	}
+	if _, err := store.RotateRefreshToken(ctx, other.Token, time.Hour); err != nil {
+		t.Errorf("rotate token of another family: %v", err)
+	}
+}
+
+func testStoreRevokeUser(t *testing.T, store Store) {
+	ctx := context.Background()
+
+	first, err := store.CreateRefreshToken(ctx, 7, time.Hour)
+	if err != nil {
+		t.Fatalf("CreateRefreshToken: %v", err)
+	}
+	second, err := store.CreateRefreshToken(ctx, 7, time.Hour)
+	if err != nil {
+		t.Fatalf("CreateRefreshToken: %v", err)
+	}
+	if err := store.SetSession(ctx, Session{ID: "second", UserID: 7, Family: second.Family}, time.Hour); err != nil {
+		t.Fatalf("SetSession: %v", err)
+	}
+	foreign, err := store.CreateRefreshToken(ctx, 8, time.Hour)
+	if err != nil {
+		t.Fatalf("CreateRefreshToken: %v", err)
+	}
+
+	// выход по одному токену отзывает только его семейство
+	if err := store.RevokeRefreshToken(ctx, first.Token); err != nil {
+		t.Fatalf("RevokeRefreshToken: %v", err)
+	}
+	if _, err := store.RotateRefreshToken(ctx, first.Token, time.Hour); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("rotate revoked token: err = %v, want ErrRefreshTokenInvalid", err)
+	}
+	assertActive(t, store, "second", true)
+
+	if err := store.RevokeUserRefreshTokens(ctx, 7); err != nil {
+		t.Fatalf("RevokeUserRefreshTokens: %v", err)
+	}
+	if _, err := store.RotateRefreshToken(ctx, second.Token, time.Hour); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("rotate token after revoking all: err = %v, want ErrRefreshTokenInvalid", err)
+	}
+	assertActive(t, store, "second", false)
+	if _, err := store.RotateRefreshToken(ctx, foreign.Token, time.Hour); err != nil {
+		t.Errorf("rotate token of another user: %v", err)
+	}
+}
+
+func assertActive(t *testing.T, store Store, sessionID string, want bool) {
+	t.Helper()
+	active, err := store.SessionActive(context.Background(), sessionID)
+	if err != nil {
+		t.Fatalf("SessionActive(%s): %v", sessionID, err)
+	}
+	if active != want {
+		t.Errorf("SessionActive(%s) = %v, want %v", sessionID, active, want)
+	}
+}
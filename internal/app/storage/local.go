@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStore - изображения в каталоге на диске (для разработки и тестов).
+// Каталог Dir раздаётся по пути URLPrefix через Handler (см. Handler.RegisterStatic).
+type LocalStore struct {
+	Dir       string
+	URLPrefix string
+}
+
+func NewLocalStore(dir, urlPrefix string) (*LocalStore, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("local image store directory must be configured")
+	}
+	if urlPrefix == "" {
+		urlPrefix = "/uploads"
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create image directory: %w", err)
+	}
+	return &LocalStore{
+		Dir:       dir,
+		URLPrefix: strings.TrimRight(urlPrefix, "/"),
+	}, nil
+}
+
+// path - путь к объекту внутри Dir; имена с ".." не допускаются
+func (s *LocalStore) path(objectName string) (string, error) {
+	cleaned := filepath.Clean("/" + objectName)
+	if cleaned == "/" {
+		return "", fmt.Errorf("invalid object name %q", objectName)
+	}
+	return filepath.Join(s.Dir, cleaned), nil
+}
+
+func (s *LocalStore) Put(ctx context.Context, objectName string, reader io.Reader, size int64, contentType string) error {
+	path, err := s.path(objectName)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, reader); err != nil {
+		file.Close()
+		os.Remove(path)
+		return err
+	}
+	return file.Close()
+}
+
+func (s *LocalStore) Remove(ctx context.Context, objectName string) error {
+	path, err := s.path(objectName)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *LocalStore) Open(ctx context.Context, objectName string) (io.ReadCloser, int64, error) {
+	path, err := s.path(objectName)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, 0, ErrObjectNotFound
+		}
+		return nil, 0, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+	return file, info.Size(), nil
+}
+
+func (s *LocalStore) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	err := filepath.WalkDir(s.Dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.Dir, path)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if !strings.HasPrefix(name, prefix) {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, ObjectInfo{
+			Name:         name,
+			Size:         info.Size(),
+			LastModified: info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return objects, nil
+}
+
+func (s *LocalStore) Move(ctx context.Context, srcName, dstName string) error {
+	srcPath, err := s.path(srcName)
+	if err != nil {
+		return err
+	}
+	dstPath, err := s.path(dstName)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dstPath), 0o755); err != nil {
+		return err
+	}
+	return os.Rename(srcPath, dstPath)
+}
+
+// Handler - публичная раздача объектов по URL вида URLPrefix + "/" + имя объекта.
+// Как и бакет MinIO, не отдаёт объекты из карантина и не показывает содержимое каталогов.
+func (s *LocalStore) Handler() http.Handler {
+	files := http.FileServer(http.Dir(s.Dir))
+	return http.StripPrefix(s.URLPrefix, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		objectName := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+		if objectName+"/" == QuarantinePrefix || strings.HasPrefix(objectName, QuarantinePrefix) {
+			http.NotFound(w, r)
+			return
+		}
+
+		filePath, err := s.path(objectName)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		if info, err := os.Stat(filePath); err != nil || info.IsDir() {
+			http.NotFound(w, r)
+			return
+		}
+		files.ServeHTTP(w, r)
+	}))
+}
+
+func (s *LocalStore) URL(objectName string) string {
+	return s.URLPrefix + "/" + strings.TrimLeft(objectName, "/")
+}
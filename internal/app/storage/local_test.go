@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLocalStoreHandlerHidesQuarantine(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir(), "/uploads")
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	ctx := context.Background()
+	for _, name := range []string{"img/ship.jpg", "img/gone.jpg"} {
+		if err := store.Put(ctx, name, strings.NewReader("jpeg"), 4, "image/jpeg"); err != nil {
+			t.Fatalf("Put(%s): %v", name, err)
+		}
+	}
+	if err := store.Move(ctx, "img/gone.jpg", QuarantinePrefix+"img/gone.jpg"); err != nil {
+		t.Fatalf("Move: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/uploads/img/ship.jpg", http.StatusOK},
+		{"/uploads/img/gone.jpg", http.StatusNotFound},
+		{"/uploads/quarantine/img/gone.jpg", http.StatusNotFound},
+		{"/uploads/img/../quarantine/img/gone.jpg", http.StatusNotFound},
+		{"/uploads/quarantine/", http.StatusNotFound},
+		{"/uploads/img/", http.StatusNotFound},
+	}
+	handler := store.Handler()
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.want {
+			t.Errorf("GET %s: status %d, want %d", tt.path, w.Code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"loading_time/internal/app/config"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// MinioStore - изображения в бакете MinIO/S3
+type MinioStore struct {
+	client        *minio.Client
+	bucket        string
+	publicBaseURL string
+}
+
+// NewMinioStore - клиент создаётся без обращения к серверу, ошибки соединения проявятся при загрузке
+func NewMinioStore(cfg config.ImageStoreConfig) (*MinioStore, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, fmt.Errorf("minio endpoint and bucket must be configured")
+	}
+
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: cfg.UseSSL,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create minio client: %w", err)
+	}
+
+	publicBaseURL := cfg.PublicBaseURL
+	if publicBaseURL == "" {
+		scheme := "http"
+		if cfg.UseSSL {
+			scheme = "https"
+		}
+		publicBaseURL = fmt.Sprintf("%s://%s/%s", scheme, cfg.Endpoint, cfg.Bucket)
+	}
+
+	return &MinioStore{
+		client:        client,
+		bucket:        cfg.Bucket,
+		publicBaseURL: strings.TrimRight(publicBaseURL, "/"),
+	}, nil
+}
+
+func (s *MinioStore) Put(ctx context.Context, objectName string, reader io.Reader, size int64, contentType string) error {
+	_, err := s.client.PutObject(ctx, s.bucket, objectName, reader, size, minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+	return err
+}
+
+func (s *MinioStore) Remove(ctx context.Context, objectName string) error {
+	return s.client.RemoveObject(ctx, s.bucket, objectName, minio.RemoveObjectOptions{})
+}
+
+func (s *MinioStore) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	for object := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if object.Err != nil {
+			return nil, object.Err
+		}
+		objects = append(objects, ObjectInfo{
+			Name:         object.Key,
+			Size:         object.Size,
+			LastModified: object.LastModified,
+		})
+	}
+	return objects, nil
+}
+
+func (s *MinioStore) Move(ctx context.Context, srcName, dstName string) error {
+	_, err := s.client.CopyObject(ctx,
+		minio.CopyDestOptions{Bucket: s.bucket, Object: dstName},
+		minio.CopySrcOptions{Bucket: s.bucket, Object: srcName},
+	)
+	if err != nil {
+		return err
+	}
+	return s.Remove(ctx, srcName)
+}
+
+func (s *MinioStore) URL(objectName string) string {
+	return s.publicBaseURL + "/" + objectName
+}
+
+func (s *MinioStore) Open(ctx context.Context, objectName string) (io.ReadCloser, int64, error) {
+	info, err := s.client.StatObject(ctx, s.bucket, objectName, minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, 0, ErrObjectNotFound
+		}
+		return nil, 0, err
+	}
+
+	object, err := s.client.GetObject(ctx, s.bucket, objectName, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, 0, err
+	}
+	return object, info.Size, nil
+}
+
+func (s *MinioStore) PresignPost(ctx context.Context, objectName string, maxSize int64, contentTypePrefix string, expiry time.Duration) (string, map[string]string, error) {
+	policy := minio.NewPostPolicy()
+	if err := policy.SetBucket(s.bucket); err != nil {
+		return "", nil, err
+	}
+	if err := policy.SetKey(objectName); err != nil {
+		return "", nil, err
+	}
+	if err := policy.SetExpires(time.Now().UTC().Add(expiry)); err != nil {
+		return "", nil, err
+	}
+	if err := policy.SetContentLengthRange(1, maxSize); err != nil {
+		return "", nil, err
+	}
+	if err := policy.SetContentTypeStartsWith(contentTypePrefix); err != nil {
+		return "", nil, err
+	}
+
+	u, formData, err := s.client.PresignedPostPolicy(ctx, policy)
+	if err != nil {
+		return "", nil, err
+	}
+	return u.String(), formData, nil
+}
+
+func (s *MinioStore) PresignGet(ctx context.Context, objectName string, expiry time.Duration) (string, error) {
+	u, err := s.client.PresignedGetObject(ctx, s.bucket, objectName, expiry, nil)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
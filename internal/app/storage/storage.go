@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"loading_time/internal/app/config"
+)
+
+const (
+	DriverMinio = "minio"
+	DriverLocal = "local"
+)
+
+// ImageStore - хранилище изображений кораблей (объекты адресуются именем вида "img/<file>")
+type ImageStore interface {
+	Put(ctx context.Context, objectName string, reader io.Reader, size int64, contentType string) error
+	Remove(ctx context.Context, objectName string) error
+	// Open - содержимое объекта и его размер; ErrObjectNotFound, если объекта нет
+	Open(ctx context.Context, objectName string) (io.ReadCloser, int64, error)
+	// List - все объекты, имена которых начинаются с prefix
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+	// Move - переименовать объект (например, перенести в карантин)
+	Move(ctx context.Context, srcName, dstName string) error
+	// URL - публичная ссылка на объект для шаблонов и клиентов API
+	URL(objectName string) string
+}
+
+// ObjectInfo - описание объекта хранилища для List
+type ObjectInfo struct {
+	Name         string
+	Size         int64
+	LastModified time.Time
+}
+
+// Presigner - хранилище, умеющее выдавать временные ссылки для прямой загрузки и скачивания
+// объектов клиентом в обход API-сервера (MinIO/S3; у LocalStore такой возможности нет)
+type Presigner interface {
+	// PresignPost - форма для загрузки объекта POST-запросом: URL и поля, которые клиент
+	// отправляет вместе с файлом (поле "file" - последним). Хранилище само отклонит файл
+	// больше maxSize байт или с Content-Type не из contentTypePrefix.
+	PresignPost(ctx context.Context, objectName string, maxSize int64, contentTypePrefix string, expiry time.Duration) (string, map[string]string, error)
+	PresignGet(ctx context.Context, objectName string, expiry time.Duration) (string, error)
+}
+
+// QuarantinePrefix - куда imagegc переносит объекты без ссылок вместо удаления.
+// Публично такие объекты не раздаются: в MinIO их закрывает политика бакета, в LocalStore - Handler.
+const QuarantinePrefix = "quarantine/"
+
+// ErrObjectNotFound - объекта с таким именем нет в хранилище
+var ErrObjectNotFound = errors.New("object not found")
+
+// FromConfig - хранилище, выбранное в config.Config
+func FromConfig(cfg config.ImageStoreConfig) (ImageStore, error) {
+	switch cfg.Driver {
+	case "", DriverMinio:
+		return NewMinioStore(cfg)
+	case DriverLocal:
+		return NewLocalStore(cfg.LocalDir, cfg.PublicBaseURL)
+	default:
+		return nil, fmt.Errorf("unknown image store driver %q", cfg.Driver)
+	}
+}